@@ -0,0 +1,526 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/api"
+	"github.com/Ka10ken1/mykadri-scraper/internal/dedupe"
+	"github.com/Ka10ken1/mykadri-scraper/internal/linkcheck"
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/Ka10ken1/mykadri-scraper/internal/posters"
+	"github.com/Ka10ken1/mykadri-scraper/internal/scraper"
+)
+
+type command struct {
+	usage string
+	desc  string
+	run   func(client *http.Client, args []string) error
+}
+
+// commandOrder is the order commands are listed in by usage.
+var commandOrder = []string{"run", "serve", "scrape", "clear", "reindex", "export", "import", "check-links", "mirror-posters", "dedupe", "cache"}
+
+var commands = map[string]command{
+	"run": {
+		usage: "run",
+		desc:  "reindex, scrape every category, then serve (the default)",
+		run:   runAll,
+	},
+	"serve": {
+		usage: "serve",
+		desc:  "serve the API and run scheduled jobs",
+		run:   serve,
+	},
+	"scrape": {
+		usage: "scrape movies|shows|all [--full|--incremental] [--pages a-b] [--dry-run [--out file]]",
+		desc:  "scrape one or every category",
+		run:   scrape,
+	},
+	"clear": {
+		usage: "clear movies|shows --yes",
+		desc:  "delete every stored item of a category",
+		run:   clearCategory,
+	},
+	"reindex": {
+		usage: "reindex",
+		desc:  "rebuild the search index and item indexes",
+		run:   reindex,
+	},
+	"export": {
+		usage: "export movies|shows [--out file]",
+		desc:  "write stored items as extended JSON lines",
+		run:   exportCategory,
+	},
+	"import": {
+		usage: "import movies|shows [--in file]",
+		desc:  "read items written by export",
+		run:   importCategory,
+	},
+	"check-links": {
+		usage: "check-links",
+		desc:  "probe every stored video URL once",
+		run:   checkLinks,
+	},
+	"mirror-posters": {
+		usage: "mirror-posters",
+		desc:  "download posters that were not mirrored yet",
+		run:   mirrorPostersCommand,
+	},
+	"dedupe": {
+		usage: "dedupe movies|shows [--merge] [--distance bits]",
+		desc:  "report titles stored more than once, or merge them",
+		run:   dedupeCategory,
+	},
+	"cache": {
+		usage: "cache stats|purge [--dir dir] [--older-than duration]",
+		desc:  "inspect or empty the scraper's response cache",
+		run:   cacheCommand,
+	},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [arguments]\n\nCommands:\n", os.Args[0])
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %s\n        %s\n", commands[name].usage, commands[name].desc)
+	}
+}
+
+// parseArgs parses the flags in args, which may come before or after the
+// positional arguments, and returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// categoryArgs resolves a category name, or "all" when allowAll is set.
+func categoryArgs(args []string, allowAll bool) ([]*scraper.Category, error) {
+	if len(args) != 1 {
+		return nil, errors.New("expected exactly one category")
+	}
+
+	if args[0] == "all" && allowAll {
+		return scraper.Categories, nil
+	}
+
+	cat, ok := scraper.CategoryByName(args[0])
+	if !ok {
+		return nil, fmt.Errorf("unknown category %q", args[0])
+	}
+	return []*scraper.Category{cat}, nil
+}
+
+// parsePages reads a listing page range such as "3-10", "5" or "20-".
+func parsePages(v string) (first, last int, err error) {
+	from, to, isRange := strings.Cut(v, "-")
+
+	if first, err = strconv.Atoi(from); err != nil || first < 1 {
+		return 0, 0, fmt.Errorf("invalid page range %q", v)
+	}
+	if !isRange {
+		return first, first, nil
+	}
+	if to == "" {
+		return first, 0, nil
+	}
+	if last, err = strconv.Atoi(to); err != nil || last < first {
+		return 0, 0, fmt.Errorf("invalid page range %q", v)
+	}
+	return first, last, nil
+}
+
+func runAll(client *http.Client, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("run takes no arguments")
+	}
+
+	if err := reindex(client, nil); err != nil {
+		return err
+	}
+	if err := scrape(client, []string{"all"}); err != nil {
+		return err
+	}
+	return serve(client, nil)
+}
+
+func serve(client *http.Client, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("serve takes no arguments")
+	}
+
+	connectMongo()
+
+	if v := os.Getenv("LINK_CHECK_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil || interval <= 0 {
+			return fmt.Errorf("invalid LINK_CHECK_INTERVAL %q", v)
+		}
+		go linkcheck.RunEvery(interval, client, categoryCollections(), linkCheckOptionsFromEnv())
+	}
+
+	sched := scrapeSchedulerFromEnv(client, scrapeOptionsFromEnv())
+	if sched != nil {
+		sched.Start()
+	}
+
+	api.RunServer(sched, posterDisk())
+	return nil
+}
+
+func scrape(client *http.Client, args []string) error {
+	fs := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	full := fs.Bool("full", false, "walk every listing page")
+	incremental := fs.Bool("incremental", false, "stop once listing pages only hold stored titles")
+	pages := fs.String("pages", "", "only visit this range of listing pages, e.g. 1-20")
+	dryRun := fs.Bool("dry-run", false, "write items as JSON lines instead of storing them; needs no MongoDB")
+	out := fs.String("out", "", "with --dry-run, file to write to instead of stdout")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	cats, err := categoryArgs(positional, true)
+	if err != nil {
+		return err
+	}
+
+	opts := scrapeOptionsFromEnv()
+	switch {
+	case *full && *incremental:
+		return errors.New("--full and --incremental are mutually exclusive")
+	case *full:
+		opts.Mode = scraper.Full
+	case *incremental:
+		opts.Mode = scraper.Incremental
+	}
+	if *pages != "" {
+		if opts.FirstPage, opts.LastPage, err = parsePages(*pages); err != nil {
+			return err
+		}
+	}
+
+	if *out != "" && !*dryRun {
+		return errors.New("--out only applies to --dry-run")
+	}
+	if *dryRun {
+		return dryRunScrape(client, cats, opts, *out)
+	}
+
+	connectMongo()
+
+	for _, cat := range cats {
+		prepareCollection(cat)
+
+		items, err := scraper.Scrape(client, cat, opts)
+		if err != nil {
+			return fmt.Errorf("scraping %s failed: %w", cat.Name, err)
+		}
+		log.Printf("Stored %d new %s", len(items), cat.Name)
+
+		mirrorPosters(client, []string{cat.Collection})
+	}
+	return nil
+}
+
+// dryRunScrape scrapes cats without MongoDB, streaming every parsed item to
+// out, or stdout if out is empty, and prints a summary to stderr at the end.
+func dryRunScrape(client *http.Client, cats []*scraper.Category, opts scraper.Options, out string) error {
+	var w io.Writer = os.Stdout
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	store := scraper.NewDryRunStore(w)
+	opts.Store = store
+
+	var scrapeErr error
+	for _, cat := range cats {
+		if _, err := scraper.Scrape(client, cat, opts); err != nil {
+			scrapeErr = fmt.Errorf("scraping %s failed: %w", cat.Name, err)
+			break
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "Dry run summary:")
+	for _, run := range store.Runs() {
+		fmt.Fprintf(os.Stderr, "  %-8s %d page(s), %d post(s), %d item(s) written, %d skipped, %d failure(s) in %s\n",
+			run.Category, run.PagesVisited, run.ItemsFound, run.New, run.Skipped, len(run.Failures),
+			run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+		for _, f := range run.Failures {
+			fmt.Fprintf(os.Stderr, "           %s: %s\n", f.URL, f.Reason)
+		}
+		for _, p := range run.Proxies {
+			fmt.Fprintf(os.Stderr, "           proxy %s: %d request(s), %d failure(s), %d rate-limited, ejected %d time(s)\n",
+				p.URL, p.Requests, p.Failures, p.RateLimited, p.Ejections)
+		}
+	}
+
+	return scrapeErr
+}
+
+func clearCategory(client *http.Client, args []string) error {
+	fs := flag.NewFlagSet("clear", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "confirm deleting every stored item")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	cats, err := categoryArgs(positional, false)
+	if err != nil {
+		return err
+	}
+	if !*yes {
+		return fmt.Errorf("clear deletes every stored %s; pass --yes to confirm", cats[0].Name)
+	}
+
+	connectMongo()
+
+	switch cats[0] {
+	case scraper.MovieCategory:
+		err = models.ClearMoviesCollection()
+	case scraper.ShowCategory:
+		err = models.ClearShowsCollection()
+	default:
+		return fmt.Errorf("clearing %s is not supported", cats[0].Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Cleared %s", cats[0].Name)
+	return nil
+}
+
+func reindex(client *http.Client, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("reindex takes no arguments")
+	}
+
+	connectMongo()
+
+	if err := models.RebuildTextIndex(); err != nil {
+		return fmt.Errorf("failed to create text index: %w", err)
+	}
+	for _, cat := range scraper.Categories {
+		prepareCollection(cat)
+	}
+	return nil
+}
+
+// prepareCollection creates the indexes of a category's collection and
+// backfills fields that older documents lack.
+func prepareCollection(cat *scraper.Category) {
+	if err := models.EnsureItemIndexes(cat.Collection); err != nil {
+		log.Fatalf("Failed to create %s indexes: %v", cat.Name, err)
+	}
+	if err := models.BackfillImdbIDs(cat.Collection); err != nil {
+		log.Printf("Failed to backfill %s IMDb IDs: %v", cat.Name, err)
+	}
+	if err := models.BackfillTitleLatin(cat.Collection); err != nil {
+		log.Printf("Failed to backfill %s Latin titles: %v", cat.Name, err)
+	}
+}
+
+func exportCategory(client *http.Client, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "", "file to write to instead of stdout")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	cats, err := categoryArgs(positional, false)
+	if err != nil {
+		return err
+	}
+
+	connectMongo()
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	n, err := models.ExportItems(cats[0].Collection, w)
+	if err != nil {
+		return fmt.Errorf("exporting %s failed after %d item(s): %w", cats[0].Name, n, err)
+	}
+
+	log.Printf("Exported %d %s", n, cats[0].Name)
+	return nil
+}
+
+func importCategory(client *http.Client, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "", "file to read from instead of stdin")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	cats, err := categoryArgs(positional, false)
+	if err != nil {
+		return err
+	}
+
+	connectMongo()
+
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	n, err := models.ImportItems(cats[0].Collection, r)
+	if err != nil {
+		return fmt.Errorf("importing %s failed after %d item(s): %w", cats[0].Name, n, err)
+	}
+
+	log.Printf("Imported %d %s", n, cats[0].Name)
+	return nil
+}
+
+func checkLinks(client *http.Client, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("check-links takes no arguments")
+	}
+
+	connectMongo()
+
+	summary, err := linkcheck.Check(client, categoryCollections(), linkCheckOptionsFromEnv())
+	if err != nil {
+		return fmt.Errorf("link check failed: %w", err)
+	}
+
+	log.Printf("Link check done: %v", summary)
+	return nil
+}
+
+func mirrorPostersCommand(client *http.Client, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("mirror-posters takes no arguments")
+	}
+
+	connectMongo()
+
+	summary, err := posters.Mirror(client, posterDisk(), categoryCollections(), posters.DefaultOptions())
+	if err != nil {
+		return fmt.Errorf("poster mirroring failed: %w", err)
+	}
+
+	log.Printf("Mirrored %d poster(s), %d failed", summary.Mirrored, summary.Failed)
+	return nil
+}
+
+func dedupeCategory(client *http.Client, args []string) error {
+	fs := flag.NewFlagSet("dedupe", flag.ContinueOnError)
+	merge := fs.Bool("merge", false, "merge every duplicate into its canonical item")
+	opts := dedupe.DefaultOptions()
+	fs.IntVar(&opts.PosterDistance, "distance", opts.PosterDistance, "bits two poster hashes may differ by")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	cats, err := categoryArgs(positional, false)
+	if err != nil {
+		return err
+	}
+
+	connectMongo()
+
+	clusters, err := dedupe.Find(cats[0].Collection, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range clusters {
+		fmt.Printf("%s (%s) [%s]\n", c.Canonical.Link, c.Canonical.Year, strings.Join(c.Reasons, ", "))
+		for _, d := range c.Duplicates {
+			fmt.Printf("    %s (%s)\n", d.Link, d.Year)
+		}
+	}
+	log.Printf("Found %d duplicate cluster(s) in %s", len(clusters), cats[0].Name)
+
+	if !*merge {
+		return nil
+	}
+	n, err := dedupe.Merge(cats[0].Collection, clusters, opts)
+	if err != nil {
+		return fmt.Errorf("merged %d duplicate(s) before failing: %w", n, err)
+	}
+	log.Printf("Merged %d duplicate(s)", n)
+	return nil
+}
+
+func cacheCommand(client *http.Client, args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	dir := fs.String("dir", os.Getenv("SCRAPE_CACHE_DIR"), "cache directory")
+	olderThan := fs.Duration("older-than", 0, "with purge, only remove entries older than this")
+
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("expected stats or purge")
+	}
+	if *dir == "" {
+		return errors.New("no cache directory: set SCRAPE_CACHE_DIR or pass --dir")
+	}
+
+	switch positional[0] {
+	case "stats":
+		stats, err := scraper.ReadCacheStats(*dir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d response(s), %.1f MiB\n", *dir, stats.Entries, float64(stats.Bytes)/(1<<20))
+		if stats.Entries > 0 {
+			fmt.Printf("oldest %s, newest %s\n", stats.Oldest.Format(time.RFC3339), stats.Newest.Format(time.RFC3339))
+		}
+		return nil
+	case "purge":
+		n, err := scraper.PurgeCache(*dir, *olderThan)
+		if err != nil {
+			return fmt.Errorf("purge failed after %d response(s): %w", n, err)
+		}
+		log.Printf("Removed %d cached response(s)", n)
+		return nil
+	default:
+		return fmt.Errorf("unknown cache action %q", positional[0])
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestParsePages(t *testing.T) {
+	tests := []struct {
+		in          string
+		first, last int
+		ok          bool
+	}{
+		{"3-10", 3, 10, true},
+		{"5", 5, 5, true},
+		{"20-", 20, 0, true},
+		{"0-4", 0, 0, false},
+		{"10-3", 0, 0, false},
+		{"a-b", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		first, last, err := parsePages(tt.in)
+		if (err == nil) != tt.ok || first != tt.first || last != tt.last {
+			t.Errorf("parsePages(%q) = %d, %d, %v", tt.in, first, last, err)
+		}
+	}
+}
+
+func TestParseArgsAllowsFlagsAfterPositionals(t *testing.T) {
+	fs := flag.NewFlagSet("scrape", flag.ContinueOnError)
+	full := fs.Bool("full", false, "")
+	pages := fs.String("pages", "", "")
+
+	positional, err := parseArgs(fs, []string{"movies", "--full", "--pages", "1-3"})
+	if err != nil {
+		t.Fatalf("parseArgs: %v", err)
+	}
+
+	if !reflect.DeepEqual(positional, []string{"movies"}) || !*full || *pages != "1-3" {
+		t.Errorf("got positional %v, full %v, pages %q", positional, *full, *pages)
+	}
+}
@@ -1,101 +1,301 @@
 package main
 
 import (
-	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
-	"github.com/Ka10ken1/mykadri-scraper/internal/api"
+	"github.com/Ka10ken1/mykadri-scraper/internal/httpclient"
+	"github.com/Ka10ken1/mykadri-scraper/internal/linkcheck"
 	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/Ka10ken1/mykadri-scraper/internal/posters"
+	"github.com/Ka10ken1/mykadri-scraper/internal/scheduler"
 	"github.com/Ka10ken1/mykadri-scraper/internal/scraper"
 	"github.com/joho/godotenv"
 )
 
+// createHTTPClientWithCustomDNS builds the shared HTTP client. It resolves
+// through DNS_SERVERS, then DNS_DOH_URL, then the system resolver unless
+// DNS_SYSTEM_FALLBACK is false; unset variables keep httpclient's defaults.
 func createHTTPClientWithCustomDNS() *http.Client {
-    resolver := &net.Resolver{
-        PreferGo: true,
-        Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-            d := net.Dialer{
-                Timeout: time.Second * 2,
-            }
-            return d.DialContext(ctx, network, "1.1.1.1:53")
-        },
-    }
+    cfg := httpclient.DefaultConfig()
 
-    dialer := &net.Dialer{
-        Resolver: resolver,
-        Timeout:  5 * time.Second,
+    if v, ok := os.LookupEnv("DNS_SERVERS"); ok {
+	cfg.Resolver.Servers = strings.FieldsFunc(v, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+    }
+    cfg.Resolver.DoHURL = os.Getenv("DNS_DOH_URL")
+    if v := os.Getenv("DNS_SYSTEM_FALLBACK"); v != "" {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+	    log.Fatalf("invalid DNS_SYSTEM_FALLBACK %q", v)
+	}
+	cfg.Resolver.System = b
     }
+    envDuration("DNS_TIMEOUT", &cfg.Resolver.Timeout)
+    envDuration("DNS_CACHE_TTL", &cfg.Resolver.CacheTTL)
 
-    transport := &http.Transport{
-        DialContext: dialer.DialContext,
+    envDuration("HTTP_TIMEOUT", &cfg.Timeout)
+    envDuration("HTTP_DIAL_TIMEOUT", &cfg.DialTimeout)
+    envDuration("HTTP_TLS_HANDSHAKE_TIMEOUT", &cfg.TLSHandshakeTimeout)
+    envDuration("HTTP_IDLE_CONN_TIMEOUT", &cfg.IdleConnTimeout)
+    envInt("HTTP_MAX_IDLE_CONNS", &cfg.MaxIdleConns)
+    envInt("HTTP_MAX_IDLE_CONNS_PER_HOST", &cfg.MaxIdleConnsPerHost)
+    envInt("HTTP_MAX_CONNS_PER_HOST", &cfg.MaxConnsPerHost)
+
+    client, err := httpclient.New(cfg)
+    if err != nil {
+	log.Fatal(err)
     }
+    return client
+}
 
-    return &http.Client{
-        Transport: transport,
-        Timeout:   10 * time.Second,
+// envDuration sets *d from the environment variable name when it is set.
+func envDuration(name string, d *time.Duration) {
+    v := os.Getenv(name)
+    if v == "" {
+	return
+    }
+    parsed, err := time.ParseDuration(v)
+    if err != nil || parsed < 0 {
+	log.Fatalf("invalid %s %q", name, v)
     }
+    *d = parsed
 }
 
+// envInt sets *n from the environment variable name when it is set.
+func envInt(name string, n *int) {
+    v := os.Getenv(name)
+    if v == "" {
+	return
+    }
+    parsed, err := strconv.Atoi(v)
+    if err != nil || parsed < 0 {
+	log.Fatalf("invalid %s %q", name, v)
+    }
+    *n = parsed
+}
 
-func main() {
+func scrapeOptionsFromEnv() scraper.Options {
+    opts := scraper.DefaultOptions()
 
-    client := createHTTPClientWithCustomDNS()
+    mode, err := scraper.ParseMode(os.Getenv("SCRAPE_MODE"))
+    if err != nil {
+	log.Fatal(err)
+    }
+    opts.Mode = mode
 
-    if err := godotenv.Load(); err != nil {
-	log.Println("No .env file found, using default env vars")
+    if v := os.Getenv("SCRAPE_STOP_AFTER"); v != "" {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+	    log.Fatalf("invalid SCRAPE_STOP_AFTER %q", v)
+	}
+	opts.StopAfter = n
     }
 
-    uri := os.Getenv("MONGO_URI")
-    db := os.Getenv("MONGO_DB")
-    coll := os.Getenv("MONGO_COLLECTION")
+    if v := os.Getenv("SCRAPE_MAX_ATTEMPTS"); v != "" {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+	    log.Fatalf("invalid SCRAPE_MAX_ATTEMPTS %q", v)
+	}
+	opts.Retry.MaxAttempts = n
+    }
 
-    if err := models.InitMongo(uri, db, coll); err != nil {
+    proxies, err := proxiesFromEnv()
+    if err != nil {
 	log.Fatal(err)
     }
+    opts.Proxies = proxies
 
-    if err := models.InitShowMongo(uri, db, "shows"); err != nil {
-	log.Fatal(err)
+    opts.Cache.Dir = os.Getenv("SCRAPE_CACHE_DIR")
+    envDuration("SCRAPE_CACHE_LISTING_TTL", &opts.Cache.ListingTTL)
+    envDuration("SCRAPE_CACHE_DETAIL_TTL", &opts.Cache.DetailTTL)
+
+    return opts
+}
+
+// proxiesFromEnv returns the proxy URLs listed in SCRAPE_PROXIES, separated
+// by commas or whitespace, followed by those in the file SCRAPE_PROXIES_FILE
+// names, one per line. Blank lines and lines starting with # are skipped.
+func proxiesFromEnv() ([]string, error) {
+    isSep := func(r rune) bool { return r == ',' || unicode.IsSpace(r) }
+    proxies := strings.FieldsFunc(os.Getenv("SCRAPE_PROXIES"), isSep)
+
+    if path := os.Getenv("SCRAPE_PROXIES_FILE"); path != "" {
+	data, err := os.ReadFile(path)
+	if err != nil {
+	    return nil, fmt.Errorf("reading SCRAPE_PROXIES_FILE: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+	    line = strings.TrimSpace(line)
+	    if line == "" || strings.HasPrefix(line, "#") {
+		continue
+	    }
+	    proxies = append(proxies, line)
+	}
     }
 
+    return proxies, nil
+}
+
+func linkCheckOptionsFromEnv() linkcheck.Options {
+    opts := linkcheck.DefaultOptions()
 
-    if err := models.RebuildTextIndex(); err != nil {
-	log.Fatalf("Failed to create text index: %v", err)
+    if v := os.Getenv("LINK_CHECK_CONCURRENCY"); v != "" {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+	    log.Fatalf("invalid LINK_CHECK_CONCURRENCY %q", v)
+	}
+	opts.Concurrency = n
     }
 
-    movies, err := scraper.ScrapeMovies(client)
-    if err != nil {
-	log.Fatal(err)
+    return opts
+}
+
+func categoryCollections() []string {
+    var collections []string
+    for _, cat := range scraper.Categories {
+	collections = append(collections, cat.Collection)
+    }
+    return collections
+}
+
+// scrapeSchedulerFromEnv returns a scheduler with an incremental scrape job
+// for every category that has a SCRAPE_SCHEDULE_<CATEGORY> cron expression,
+// or nil if none has.
+func scrapeSchedulerFromEnv(client *http.Client, opts scraper.Options) *scheduler.Scheduler {
+    var jitter time.Duration
+    if v := os.Getenv("SCRAPE_SCHEDULE_JITTER"); v != "" {
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+	    log.Fatalf("invalid SCRAPE_SCHEDULE_JITTER %q", v)
+	}
+	jitter = d
     }
 
-    if len(movies) > 0 {
-	if err := models.InsertMovies(movies); err != nil {
+    opts.Mode = scraper.Incremental
+
+    var sched *scheduler.Scheduler
+    for _, cat := range scraper.Categories {
+	spec := os.Getenv("SCRAPE_SCHEDULE_" + strings.ToUpper(cat.Name))
+	if spec == "" {
+	    continue
+	}
+
+	if sched == nil {
+	    sched = scheduler.New()
+	}
+	err := sched.Add(scheduler.Job{
+	    Name:     cat.Name,
+	    Schedule: spec,
+	    Jitter:   jitter,
+	    Run: func() error {
+		items, err := scraper.Scrape(client, cat, opts)
+		if err != nil {
+		    return err
+		}
+		log.Printf("Stored %d new %s", len(items), cat.Name)
+		mirrorPosters(client, []string{cat.Collection})
+		return nil
+	    },
+	})
+	if err != nil {
 	    log.Fatal(err)
 	}
-    } else {
-	log.Println("No new movies to insert, skipping DB insert.")
     }
 
-    shows, err := scraper.ScrapeShows(client)
+    return sched
+}
+
+// posterDisk opens the directory mirrored posters are kept in, POSTER_DIR
+// or ./posters.
+func posterDisk() *posters.Disk {
+    dir := os.Getenv("POSTER_DIR")
+    if dir == "" {
+	dir = "posters"
+    }
+
+    disk, err := posters.NewDisk(dir)
     if err != nil {
-	log.Fatal("Show scrape failed:", err)
+	log.Fatalf("Failed to open poster directory: %v", err)
     }
-    if len(shows) > 0 {
-	if err := models.InsertShows(shows); err != nil {
-	    log.Fatal("Show insert failed:", err)
-	}
-    } else {
-	log.Println("No new shows to insert.")
+    return disk
+}
+
+// mirrorPosters mirrors the new or changed posters of collections. Failures
+// are only logged; the posters are tried again on the next call.
+func mirrorPosters(client *http.Client, collections []string) {
+    summary, err := posters.Mirror(client, posterDisk(), collections, posters.DefaultOptions())
+    if err != nil {
+	log.Printf("Poster mirroring failed: %v", err)
+	return
+    }
+    if summary.Mirrored > 0 || summary.Failed > 0 {
+	log.Printf("Mirrored %d poster(s), %d failed", summary.Mirrored, summary.Failed)
     }
+}
 
+var mongoOnce sync.Once
 
-    api.RunServer()
+// connectMongo connects to MongoDB the first time it is called. Commands
+// call it themselves so that those that do not need the database, such as a
+// dry-run scrape, can run without one.
+func connectMongo() {
+    mongoOnce.Do(func() {
+	uri := os.Getenv("MONGO_URI")
+	db := os.Getenv("MONGO_DB")
+	coll := scraper.MovieCategory.Collection
 
-    // models.ClearMoviesCollection()
-    // models.ClearShowsCollection()
+	if err := models.InitMongo(uri, db, coll); err != nil {
+	    log.Fatal(err)
+	}
 
+	if err := models.InitShowMongo(uri, db, "shows"); err != nil {
+	    log.Fatal(err)
+	}
+    })
 }
 
+func main() {
+    name, args := "run", []string(nil)
+    if len(os.Args) > 1 {
+	name, args = os.Args[1], os.Args[2:]
+    }
+    if name == "help" || name == "-h" || name == "--help" {
+	usage()
+	return
+    }
+
+    cmd, ok := commands[name]
+    if !ok {
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+    }
+
+    if err := godotenv.Load(); err != nil {
+	log.Println("No .env file found, using default env vars")
+    }
+
+    client := createHTTPClientWithCustomDNS()
+
+    // Commands that need no MongoDB, such as a dry-run scrape, keep the
+    // built-in collection name unless it is overridden.
+    if coll := os.Getenv("MONGO_COLLECTION"); coll != "" {
+	scraper.MovieCategory.Collection = coll
+    }
+
+    if err := cmd.run(client, args); err != nil {
+	if errors.Is(err, flag.ErrHelp) {
+	    return
+	}
+	log.Fatal(err)
+    }
+}
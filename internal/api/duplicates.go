@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/dedupe"
+	"github.com/Ka10ken1/mykadri-scraper/internal/scraper"
+	"github.com/gin-gonic/gin"
+)
+
+// duplicatesTTL is how long a duplicates report is served before it is
+// computed again. Finding duplicates reads every stored title and compares
+// them pairwise, so it is not done per request.
+const duplicatesTTL = 15 * time.Minute
+
+type duplicatesReport struct {
+	clusters   []dedupe.Cluster
+	computedAt time.Time
+}
+
+var duplicatesCache = struct {
+	// mu is held while a report is computed, so concurrent requests wait
+	// for it rather than computing it again.
+	mu      sync.Mutex
+	reports map[string]duplicatesReport
+}{reports: make(map[string]duplicatesReport)}
+
+// GetDuplicates reports the clusters of titles stored more than once in
+// the category named by ?category=. The report is cached for
+// duplicatesTTL. Merging them is left to the dedupe command.
+func GetDuplicates(c *gin.Context) {
+	cat, ok := scraper.CategoryByName(c.Query("category"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter 'category' must be movies or shows"})
+		return
+	}
+
+	clusters, err := cachedDuplicates(cat.Collection)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to find duplicates"})
+		return
+	}
+	c.JSON(http.StatusOK, clusters)
+}
+
+func cachedDuplicates(collection string) ([]dedupe.Cluster, error) {
+	duplicatesCache.mu.Lock()
+	defer duplicatesCache.mu.Unlock()
+
+	if r, ok := duplicatesCache.reports[collection]; ok && time.Since(r.computedAt) < duplicatesTTL {
+		return r.clusters, nil
+	}
+
+	clusters, err := dedupe.Find(collection, dedupe.DefaultOptions())
+	if err != nil {
+		return nil, err
+	}
+	if clusters == nil {
+		clusters = []dedupe.Cluster{}
+	}
+	duplicatesCache.reports[collection] = duplicatesReport{clusters: clusters, computedAt: time.Now()}
+	return clusters, nil
+}
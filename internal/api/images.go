@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/posters"
+	"github.com/gin-gonic/gin"
+)
+
+// GetImage returns a handler serving mirrored posters from disk by content
+// hash. ?w= asks for a thumbnail of that width, one of
+// posters.ThumbnailWidths. A nil disk has no posters.
+func GetImage(disk *posters.Disk) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if disk == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+			return
+		}
+
+		hash := c.Param("hash")
+
+		var (
+			path string
+			err  error
+		)
+		if w := c.Query("w"); w != "" {
+			width, convErr := strconv.Atoi(w)
+			if convErr != nil || !posters.ValidThumbnailWidth(width) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("'w' must be one of %v", posters.ThumbnailWidths)})
+				return
+			}
+			path, err = disk.Thumbnail(hash, width)
+		} else {
+			path, err = disk.Path(hash)
+		}
+
+		if errors.Is(err, posters.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get image"})
+			return
+		}
+
+		// The content behind a hash never changes.
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.File(path)
+	}
+}
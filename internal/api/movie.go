@@ -4,10 +4,17 @@ import (
 	"net/http"
 	"github.com/gin-gonic/gin"
 	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/Ka10ken1/mykadri-scraper/internal/posters"
 )
 
 func GetMovies(c *gin.Context) {
-	movies, err := models.GetAllMovies()
+	filter, err := listFilterParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	movies, err := models.GetAllMovies(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get movies"})
 		return
@@ -36,12 +43,42 @@ func GetMovieByID(c *gin.Context) {
 }
 
 
+func GetMovieByImdbID(c *gin.Context) {
+	imdbID := c.Param("imdbId")
+
+	movie, err := models.GetMovieByImdbID(imdbID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get movie"})
+		return
+	}
+
+	if movie == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "movie not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, movie)
+}
+
 func GetMovieImages(c *gin.Context) {
-    images, err := models.GetAllMovieImages()
+    filter, err := listFilterParams(c)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
+
+    images, err := models.GetAllMovieImages(filter)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get movie images"})
         return
     }
+
+    // Mirrored posters are served by us instead of hotlinked.
+    for i := range images {
+        if images[i].ImageHash != "" {
+            images[i].Image = posters.URL(images[i].ImageHash)
+        }
+    }
     c.JSON(http.StatusOK, images)
 }
 
@@ -59,8 +96,16 @@ func ShowMoviePage(c *gin.Context) {
 		"Title":        movie.Title,
 		"EnglishTitle": movie.TitleEnglish,
 		"VideoURL":     movie.VideoURL,
+		"Sources":      movie.Sources,
 		"Image":        movie.Image,
 		"Year":         movie.Year,
+		"Description":  movie.Description,
+		"Genres":       movie.Genres,
+		"Rating":       movie.Rating,
+		"Runtime":      movie.Runtime,
+		"Country":      movie.Country,
+		"Director":     movie.Director,
+		"Actors":       movie.Actors,
 	})
 }
 
@@ -71,7 +116,13 @@ func GetMoviesByTitle(c *gin.Context) {
 		return
 	}
 
-	movies, err := models.SearchMoviesByTitle(query)
+	filter, err := listFilterParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	movies, err := models.SearchMoviesByTitle(query, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search movies"})
 		return
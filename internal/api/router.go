@@ -1,27 +1,44 @@
 package api
 
 import (
+	"fmt"
 	"log"
+	"strconv"
 
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/Ka10ken1/mykadri-scraper/internal/posters"
+	"github.com/Ka10ken1/mykadri-scraper/internal/scheduler"
 	"github.com/gin-gonic/gin"
 )
 
-func RunServer() {
+// RunServer serves the API and the frontend. sched, if not nil, is the
+// scheduler whose jobs /api/schedule reports on, and images, if not nil,
+// holds the mirrored posters /api/images serves.
+func RunServer(sched *scheduler.Scheduler, images *posters.Disk) {
 	const port = ":8080"
 	r := gin.Default()
 
 	r.GET("/api/movies", GetMovies)
 	r.GET("/api/movies/:id", GetMovieByID)
+	r.GET("/api/movies/imdb/:imdbId", GetMovieByImdbID)
 	r.GET("/api/movie-images", GetMovieImages)
 	r.GET("/api/search", GetMoviesByTitle)
 	r.GET("/api/movie/:id", ShowMoviePage)
 
 	r.GET("/api/shows", GetShows)
 	r.GET("/api/shows/:id", GetShowByID)
+	r.GET("/api/shows/:id/episodes", GetShowEpisodes)
+	r.GET("/api/shows/imdb/:imdbId", GetShowByImdbID)
 	r.GET("/api/shows/images", GetShowImages)
 	r.GET("/api/shows/search", GetShowsByTitle)
 	r.GET("/api/show/:id", ShowShowPage)
 
+	r.GET("/api/scrape-runs", GetScrapeRuns)
+	r.GET("/api/scrape-runs/:id", GetScrapeRunByID)
+	r.GET("/api/schedule", GetSchedule(sched))
+	r.GET("/api/images/:hash", GetImage(images))
+	r.GET("/api/duplicates", GetDuplicates)
+
 	r.Static("/static", "./web")
 
 	
@@ -46,3 +63,27 @@ func RunServer() {
 	}
 }
 
+// listFilterParams reads the optional query parameters of the list
+// endpoints: ?available=true leaves out titles whose embed was found dead
+// and ?includeDelisted=true keeps titles no longer listed on mykadri.tv.
+func listFilterParams(c *gin.Context) (models.ListFilter, error) {
+	var filter models.ListFilter
+
+	for name, dst := range map[string]*bool{
+		"available":       &filter.OnlyAvailable,
+		"includeDelisted": &filter.IncludeDelisted,
+	} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid value %q for '%s'", v, name)
+		}
+		*dst = b
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSchedule returns a handler listing the scheduled jobs of sched with
+// their last and next run times. A nil sched has no jobs.
+func GetSchedule(sched *scheduler.Scheduler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sched == nil {
+			c.JSON(http.StatusOK, []scheduler.Status{})
+			return
+		}
+		c.JSON(http.StatusOK, sched.Statuses())
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultScrapeRunLimit = 50
+
+// GetScrapeRuns lists recent scrape runs, newest first. ?category= narrows
+// them down to one category and ?limit= caps how many are returned.
+func GetScrapeRuns(c *gin.Context) {
+	limit := int64(defaultScrapeRunLimit)
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter 'limit' must be a positive number"})
+			return
+		}
+		limit = n
+	}
+
+	runs, err := models.GetScrapeRuns(c.Query("category"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scrape runs"})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+func GetScrapeRunByID(c *gin.Context) {
+	id := c.Param("id")
+	if !primitive.IsValidObjectID(id) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "scrape run not found"})
+		return
+	}
+
+	run, err := models.GetScrapeRunByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get scrape run"})
+		return
+	}
+
+	if run == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "scrape run not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -5,10 +5,17 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/Ka10ken1/mykadri-scraper/internal/posters"
 )
 
 func GetShows(c *gin.Context) {
-	shows, err := models.GetAllShows()
+	filter, err := listFilterParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shows, err := models.GetAllShows(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get shows"})
 		return
@@ -33,19 +40,66 @@ func GetShowByID(c *gin.Context) {
 	c.JSON(http.StatusOK, show)
 }
 
+func GetShowByImdbID(c *gin.Context) {
+	imdbID := c.Param("imdbId")
+
+	show, err := models.GetShowByImdbID(imdbID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get show"})
+		return
+	}
+
+	if show == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "show not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, show)
+}
+
+func GetShowEpisodes(c *gin.Context) {
+	id := c.Param("id")
+
+	show, err := models.GetShowByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get show"})
+		return
+	}
+
+	if show == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "show not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, show.Seasons)
+}
+
 func GetShowImages(c *gin.Context) {
-	images, err := models.GetAllShowImages()
+	filter, err := listFilterParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	images, err := models.GetAllShowImages(filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get show images"})
 		return
 	}
+
+	// Mirrored posters are served by us instead of hotlinked.
+	for i := range images {
+		if images[i].ImageHash != "" {
+			images[i].Image = posters.URL(images[i].ImageHash)
+		}
+	}
 	c.JSON(http.StatusOK, images)
 }
 
 func ShowShowPage(c *gin.Context) {
 	id := c.Param("id")
 	show, err := models.GetShowByID(id)
-	if err != nil {
+	if err != nil || show == nil {
 		c.String(http.StatusNotFound, "Show not found")
 		return
 	}
@@ -54,8 +108,17 @@ func ShowShowPage(c *gin.Context) {
 		"Title":        show.Title,
 		"EnglishTitle": show.TitleEnglish,
 		"VideoURL":     show.VideoURL,
+		"Sources":      show.Sources,
 		"Image":        show.Image,
 		"Year":         show.Year,
+		"Description":  show.Description,
+		"Genres":       show.Genres,
+		"Rating":       show.Rating,
+		"Runtime":      show.Runtime,
+		"Country":      show.Country,
+		"Director":     show.Director,
+		"Actors":       show.Actors,
+		"Seasons":      show.Seasons,
 	})
 }
 
@@ -66,7 +129,13 @@ func GetShowsByTitle(c *gin.Context) {
 		return
 	}
 
-	shows, err := models.SearchShowsByTitle(query)
+	filter, err := listFilterParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	shows, err := models.SearchShowsByTitle(query, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search shows"})
 		return
@@ -0,0 +1,286 @@
+// Package dedupe finds titles that mykadri.tv lists more than once under
+// different links and merges them into one document.
+package dedupe
+
+import (
+	"cmp"
+	"fmt"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/Ka10ken1/mykadri-scraper/internal/translit"
+)
+
+type Candidate = models.DedupeCandidate
+
+// Reasons two items are taken for duplicates.
+const (
+	SameImdbID = "imdb"
+	SameTitle  = "title"
+	SamePoster = "poster"
+)
+
+type Options struct {
+	// PosterDistance is how many bits two poster perceptual hashes may
+	// differ by and still count as the same poster.
+	PosterDistance int
+	// Store overrides where candidates come from and merges go; by default
+	// that is the stored collection itself.
+	Store Store
+}
+
+func DefaultOptions() Options {
+	return Options{
+		PosterDistance: 6,
+	}
+}
+
+// Cluster is a group of items that are the same title. Canonical is the
+// one to keep.
+type Cluster struct {
+	Canonical  Candidate   `json:"canonical"`
+	Duplicates []Candidate `json:"duplicates"`
+	// Reasons lists why members were matched: SameImdbID, SameTitle or
+	// SamePoster.
+	Reasons []string `json:"reasons"`
+}
+
+// Find returns the duplicate clusters in collection, largest first.
+func Find(collection string, opts Options) ([]Cluster, error) {
+	candidates, err := opts.store().Candidates(collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", collection, err)
+	}
+	return cluster(candidates, opts.PosterDistance), nil
+}
+
+// Merge merges every cluster into its canonical item and returns how many
+// duplicates were merged away. It stops at the first failure.
+func Merge(collection string, clusters []Cluster, opts Options) (int, error) {
+	store := opts.store()
+
+	merged := 0
+	for _, c := range clusters {
+		links := make([]string, 0, len(c.Duplicates))
+		for _, d := range c.Duplicates {
+			links = append(links, d.Link)
+		}
+		if err := store.Merge(collection, c.Canonical.Link, links); err != nil {
+			return merged, fmt.Errorf("failed to merge into %s: %w", c.Canonical.Link, err)
+		}
+		merged += len(links)
+	}
+	return merged, nil
+}
+
+// matcher decides whether two candidates are the same title.
+type matcher struct {
+	candidates []Candidate
+	// english and georgian are the normalized English titles and the
+	// romanized Georgian ones.
+	english        []string
+	georgian       []string
+	hashes         []uint64
+	hasHash        []bool
+	posterDistance int
+}
+
+func newMatcher(candidates []Candidate, posterDistance int) *matcher {
+	m := &matcher{
+		candidates:     candidates,
+		english:        make([]string, len(candidates)),
+		georgian:       make([]string, len(candidates)),
+		hashes:         make([]uint64, len(candidates)),
+		hasHash:        make([]bool, len(candidates)),
+		posterDistance: posterDistance,
+	}
+	for i, c := range candidates {
+		m.english[i] = normalizeTitle(c.TitleEnglish)
+		m.georgian[i] = normalizeTitle(translit.SearchKey(c.Title))
+		if h, err := strconv.ParseUint(c.ImagePHash, 16, 64); err == nil && len(c.ImagePHash) == 16 {
+			m.hashes[i], m.hasHash[i] = h, true
+		}
+	}
+	return m
+}
+
+// match returns why candidates i and j are the same title, or nil if they
+// are not. A shared IMDb ID is enough on its own. Otherwise the titles must
+// be the same and the years must not conflict; posters only confirm or
+// veto, since unrelated dark posters or placeholders hash alike:
+//   - when both have a poster hash, the posters must match, and then a
+//     missing year is accepted;
+//   - when one has none, both years must be known and equal.
+func (m *matcher) match(i, j int) []string {
+	a, b := m.candidates[i], m.candidates[j]
+
+	if a.ImdbID != "" && a.ImdbID == b.ImdbID {
+		return []string{SameImdbID}
+	}
+	if !m.sameTitle(i, j) {
+		return nil
+	}
+	// A remake has the title, and maybe the poster style, but not the year
+	// of the original.
+	if a.Year != "" && b.Year != "" && a.Year != b.Year {
+		return nil
+	}
+
+	if m.hasHash[i] && m.hasHash[j] {
+		if bits.OnesCount64(m.hashes[i]^m.hashes[j]) > m.posterDistance {
+			return nil
+		}
+		return []string{SamePoster, SameTitle}
+	}
+	if a.Year == "" || a.Year != b.Year {
+		return nil
+	}
+	return []string{SameTitle}
+}
+
+// sameTitle compares the English titles of i and j, or their Georgian ones
+// romanized if either has no English title.
+func (m *matcher) sameTitle(i, j int) bool {
+	if m.english[i] != "" && m.english[j] != "" {
+		return m.english[i] == m.english[j]
+	}
+	return m.georgian[i] != "" && m.georgian[i] == m.georgian[j]
+}
+
+// cluster groups candidates with complete linkage: two groups are joined
+// only if every member of one matches every member of the other, so an
+// item without a year cannot chain the original and its remake together.
+// Stronger matches are joined first.
+func cluster(candidates []Candidate, posterDistance int) []Cluster {
+	m := newMatcher(candidates, posterDistance)
+
+	type pair struct {
+		i, j    int
+		reasons []string
+	}
+	var pairs []pair
+	for i := range candidates {
+		for j := i + 1; j < len(candidates); j++ {
+			if reasons := m.match(i, j); reasons != nil {
+				pairs = append(pairs, pair{i, j, reasons})
+			}
+		}
+	}
+	strength := func(p pair) int {
+		if p.reasons[0] == SameImdbID {
+			return 3
+		}
+		return len(p.reasons)
+	}
+	slices.SortStableFunc(pairs, func(a, b pair) int {
+		return cmp.Compare(strength(b), strength(a))
+	})
+
+	group := make([]int, len(candidates))
+	members := make(map[int][]int, len(candidates))
+	for i := range candidates {
+		group[i] = i
+		members[i] = []int{i}
+	}
+	reasons := make(map[int]map[string]bool)
+
+	for _, p := range pairs {
+		gi, gj := group[p.i], group[p.j]
+		if gi != gj {
+			if !m.allMatch(members[gi], members[gj]) {
+				continue
+			}
+			for _, k := range members[gj] {
+				group[k] = gi
+			}
+			members[gi] = append(members[gi], members[gj]...)
+			delete(members, gj)
+			for r := range reasons[gj] {
+				addReason(reasons, gi, r)
+			}
+			delete(reasons, gj)
+		}
+		for _, r := range p.reasons {
+			addReason(reasons, gi, r)
+		}
+	}
+
+	var clusters []Cluster
+	for root, idx := range members {
+		if len(idx) < 2 {
+			continue
+		}
+
+		group := make([]Candidate, 0, len(idx))
+		for _, i := range idx {
+			group = append(group, candidates[i])
+		}
+		slices.SortFunc(group, preferCanonical)
+		c := Cluster{Canonical: group[0], Duplicates: group[1:]}
+		for r := range reasons[root] {
+			c.Reasons = append(c.Reasons, r)
+		}
+		slices.Sort(c.Reasons)
+		clusters = append(clusters, c)
+	}
+
+	slices.SortFunc(clusters, func(a, b Cluster) int {
+		return cmp.Or(
+			cmp.Compare(len(b.Duplicates), len(a.Duplicates)),
+			cmp.Compare(a.Canonical.Link, b.Canonical.Link),
+		)
+	})
+	return clusters
+}
+
+// allMatch reports whether every candidate in a matches every one in b.
+func (m *matcher) allMatch(a, b []int) bool {
+	for _, i := range a {
+		for _, j := range b {
+			if m.match(i, j) == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func addReason(reasons map[int]map[string]bool, root int, reason string) {
+	if reasons[root] == nil {
+		reasons[root] = make(map[string]bool)
+	}
+	reasons[root][reason] = true
+}
+
+// preferCanonical orders the members of a cluster best first: items with
+// an IMDb ID, then those with more players, then the oldest.
+func preferCanonical(a, b Candidate) int {
+	hasImdb := func(c Candidate) int {
+		if c.ImdbID != "" {
+			return 0
+		}
+		return 1
+	}
+	return cmp.Or(
+		cmp.Compare(hasImdb(a), hasImdb(b)),
+		cmp.Compare(len(b.Sources), len(a.Sources)),
+		cmp.Compare(a.ID.Hex(), b.ID.Hex()),
+	)
+}
+
+// normalizeTitle lowercases title, turns punctuation into spaces, drops a
+// leading article and collapses whitespace, so that "The Matrix",
+// "Matrix" and "matrix." compare equal.
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(fields) > 1 && (fields[0] == "the" || fields[0] == "a" || fields[0] == "an") {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
@@ -0,0 +1,172 @@
+package dedupe
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recordingStore serves fixed candidates and records the merges it is asked
+// for instead of applying them.
+type recordingStore struct {
+	candidates []Candidate
+	merges     map[string][]string
+}
+
+func (m *recordingStore) Candidates(string) ([]Candidate, error) {
+	return m.candidates, nil
+}
+
+func (m *recordingStore) Merge(_, canonicalLink string, aliasLinks []string) error {
+	if m.merges == nil {
+		m.merges = make(map[string][]string)
+	}
+	m.merges[canonicalLink] = aliasLinks
+	return nil
+}
+
+func candidate(n byte, link, title, year string) Candidate {
+	var id primitive.ObjectID
+	id[11] = n
+	return Candidate{ID: id, Link: link, TitleEnglish: title, Year: year}
+}
+
+func links(cs []Candidate) []string {
+	var out []string
+	for _, c := range cs {
+		out = append(out, c.Link)
+	}
+	return out
+}
+
+func TestFindClustersDuplicates(t *testing.T) {
+	inception := candidate(1, "/1-inception", "Inception", "2010")
+	inception.Title = "დასაწყისი"
+	inception.ImagePHash = "f0f0f0f0f0f0f0f0"
+	inceptionHD := candidate(2, "/2-inception-hd", "Inception.", "2010")
+	inceptionHD.Title = "დასაწყისი"
+	inceptionHD.Sources = []models.VideoSource{{Provider: "a"}, {Provider: "b"}}
+	inceptionHD.ImagePHash = "f0f0f0f0f0f0f0f1"
+	// Listed under its Georgian title only, with a re-encoded poster; it is
+	// compared with the others by their Georgian titles.
+	dabadeba := candidate(3, "/3-dabadeba", "", "2010")
+	dabadeba.Title = "დასაწყისი"
+	dabadeba.ImagePHash = "f0f0f0f0f0f0f0f3"
+	remake := candidate(4, "/4-inception-2025", "Inception", "2025")
+	remake.ImagePHash = "f0f0f0f0f0f0f0f0"
+
+	matrix := candidate(5, "/5-the-matrix", "The Matrix", "1999")
+	matrix.ImdbID = "tt0133093"
+	// Neither has a poster hash, so title and year decide.
+	matrixAgain := candidate(6, "/6-matrix", "Matrix", "1999")
+
+	other := candidate(7, "/7-interstellar", "Interstellar", "2014")
+	other.ImagePHash = "0f0f0f0f0f0f0f0f"
+
+	store := &recordingStore{candidates: []Candidate{inception, inceptionHD, dabadeba, remake, matrix, matrixAgain, other}}
+	opts := DefaultOptions()
+	opts.Store = store
+
+	clusters, err := Find("movies", opts)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2: %+v", len(clusters), clusters)
+	}
+
+	// The item with more players wins when none has an IMDb ID.
+	first := clusters[0]
+	if first.Canonical.Link != "/2-inception-hd" ||
+		!reflect.DeepEqual(links(first.Duplicates), []string{"/1-inception", "/3-dabadeba"}) ||
+		!reflect.DeepEqual(first.Reasons, []string{SamePoster, SameTitle}) {
+		t.Errorf("first cluster = %s + %v for %v", first.Canonical.Link, links(first.Duplicates), first.Reasons)
+	}
+
+	second := clusters[1]
+	if second.Canonical.Link != "/5-the-matrix" ||
+		!reflect.DeepEqual(links(second.Duplicates), []string{"/6-matrix"}) ||
+		!reflect.DeepEqual(second.Reasons, []string{SameTitle}) {
+		t.Errorf("second cluster = %s + %v for %v", second.Canonical.Link, links(second.Duplicates), second.Reasons)
+	}
+
+	n, err := Merge("movies", clusters, opts)
+	if err != nil || n != 3 {
+		t.Fatalf("Merge = %d, %v, want 3 merged", n, err)
+	}
+	if got := store.merges["/5-the-matrix"]; !reflect.DeepEqual(got, []string{"/6-matrix"}) {
+		t.Errorf("merged into the matrix: %v", got)
+	}
+}
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := map[string]string{
+		"The Matrix":              "matrix",
+		"  Matrix. ":              "matrix",
+		"Spider-Man: No Way Home": "spider man no way home",
+		"The":                     "the",
+		"ოპენჰაიმერი":             "ოპენჰაიმერი",
+	}
+	for in, want := range tests {
+		if got := normalizeTitle(in); got != want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFindDoesNotChainAcrossYears(t *testing.T) {
+	original := candidate(1, "/1-inception", "Inception", "2010")
+	noYear := candidate(2, "/2-inception", "Inception", "")
+	remake := candidate(3, "/3-inception", "Inception", "2025")
+	for _, c := range []*Candidate{&original, &noYear, &remake} {
+		c.ImagePHash = "f0f0f0f0f0f0f0f0"
+	}
+
+	// Two different films of the same name and year.
+	alone := candidate(4, "/4-alone", "Alone", "2020")
+	alone.ImagePHash = "0123456789abcdef"
+	otherAlone := candidate(5, "/5-alone", "Alone", "2020")
+	otherAlone.ImagePHash = "fedcba9876543210"
+
+	opts := DefaultOptions()
+	opts.Store = &recordingStore{candidates: []Candidate{original, noYear, remake, alone, otherAlone}}
+
+	clusters, err := Find("movies", opts)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(clusters) != 1 {
+		t.Fatalf("got %d clusters, want 1: %+v", len(clusters), clusters)
+	}
+
+	got := append([]string{clusters[0].Canonical.Link}, links(clusters[0].Duplicates)...)
+	if len(got) != 2 || slices.Contains(got, "/3-inception") || !slices.Contains(got, "/2-inception") {
+		t.Errorf("cluster = %v, want the year-less copy with only one of the dated ones", got)
+	}
+}
+
+func TestFindNeedsTheSameTitle(t *testing.T) {
+	// Different films of the same year whose posters hash alike, such as
+	// two dark posters or the site's placeholder.
+	a := candidate(1, "/1-alien", "Alien", "1979")
+	b := candidate(2, "/2-apocalypse-now", "Apocalypse Now", "1979")
+	c := candidate(3, "/3-kvanti", "", "1979")
+	c.Title = "კვანტი"
+	for _, m := range []*Candidate{&a, &b, &c} {
+		m.ImagePHash = "0000000000000000"
+	}
+
+	opts := DefaultOptions()
+	opts.Store = &recordingStore{candidates: []Candidate{a, b, c}}
+
+	clusters, err := Find("movies", opts)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(clusters) != 0 {
+		t.Errorf("got clusters %+v, want none", clusters)
+	}
+}
@@ -0,0 +1,29 @@
+package dedupe
+
+import "github.com/Ka10ken1/mykadri-scraper/internal/models"
+
+// Store supplies the candidates Find compares and applies the merges Merge
+// decides on, one cluster at a time.
+type Store interface {
+	Candidates(collection string) ([]Candidate, error)
+	Merge(collection, canonicalLink string, aliasLinks []string) error
+}
+
+// modelsStore reads candidates from and merges clusters in the item
+// collections of the models package.
+type modelsStore struct{}
+
+func (modelsStore) Candidates(collection string) ([]Candidate, error) {
+	return models.GetDedupeCandidates(collection)
+}
+
+func (modelsStore) Merge(collection, canonicalLink string, aliasLinks []string) error {
+	return models.MergeItems(collection, canonicalLink, aliasLinks)
+}
+
+func (o Options) store() Store {
+	if o.Store != nil {
+		return o.Store
+	}
+	return modelsStore{}
+}
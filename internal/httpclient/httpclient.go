@@ -0,0 +1,65 @@
+// Package httpclient builds the HTTP client the scraper and link checker
+// share, with a configurable resolver and connection pool.
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Resolver ResolverConfig
+
+	// Timeout bounds a whole request, including reading the body.
+	Timeout             time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits connections per host. Zero means no limit.
+	MaxConnsPerHost int
+}
+
+// DefaultConfig resolves through Cloudflare's DNS and falls back to the
+// system resolver.
+func DefaultConfig() Config {
+	return Config{
+		Resolver: ResolverConfig{
+			Servers:  []string{"1.1.1.1:53"},
+			System:   true,
+			Timeout:  2 * time.Second,
+			CacheTTL: 5 * time.Minute,
+		},
+		Timeout:             10 * time.Second,
+		DialTimeout:         5 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+	}
+}
+
+func New(cfg Config) (*http.Client, error) {
+	resolver, err := NewResolver(cfg.Resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout}
+
+	transport := &http.Transport{
+		DialContext:         resolver.DialContext(dialer),
+		TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.MaxConnsPerHost,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}, nil
+}
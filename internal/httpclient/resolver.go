@@ -0,0 +1,256 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// ResolverConfig says where host names are looked up. Sources are tried in
+// order: each of Servers, then DoHURL, then the system resolver.
+type ResolverConfig struct {
+	// Servers are plain DNS servers as host or host:port; port 53 is
+	// assumed when it is missing.
+	Servers []string
+	// DoHURL is an optional DNS-over-HTTPS endpoint that accepts RFC 8484
+	// POST queries, such as https://1.1.1.1/dns-query. Its own host name is
+	// resolved by the system resolver.
+	DoHURL string
+	// System enables the system resolver as the last resort.
+	System bool
+	// Timeout bounds a lookup against each single source.
+	Timeout time.Duration
+	// CacheTTL is how long successful lookups are reused. Zero disables the
+	// cache.
+	CacheTTL time.Duration
+}
+
+type source struct {
+	name   string
+	lookup func(ctx context.Context, host string) ([]string, error)
+}
+
+type cacheEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// Resolver looks up host names through a chain of sources and caches the
+// answers.
+type Resolver struct {
+	sources []source
+	timeout time.Duration
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+func NewResolver(cfg ResolverConfig) (*Resolver, error) {
+	r := &Resolver{
+		timeout: cfg.Timeout,
+		ttl:     cfg.CacheTTL,
+		now:     time.Now,
+		cache:   make(map[string]cacheEntry),
+	}
+
+	for _, server := range cfg.Servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		r.sources = append(r.sources, source{name: server, lookup: dnsLookup(server)})
+	}
+	if cfg.DoHURL != "" {
+		r.sources = append(r.sources, source{name: cfg.DoHURL, lookup: dohLookup(cfg.DoHURL, &http.Client{Timeout: cfg.Timeout})})
+	}
+	if cfg.System {
+		r.sources = append(r.sources, source{name: "system resolver", lookup: net.DefaultResolver.LookupHost})
+	}
+
+	if len(r.sources) == 0 {
+		return nil, errors.New("no DNS servers, DNS-over-HTTPS URL or system resolver configured")
+	}
+	return r, nil
+}
+
+// LookupHost returns the addresses of host from the cache or the first
+// source that knows it.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if net.ParseIP(host) != nil {
+		return []string{host}, nil
+	}
+
+	r.mu.Lock()
+	entry, ok := r.cache[host]
+	r.mu.Unlock()
+	if ok && r.now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	var errs []error
+	for _, src := range r.sources {
+		addrs, err := r.lookupWith(ctx, src, host)
+		if err == nil {
+			r.store(host, addrs)
+			return addrs, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", src.name, err))
+	}
+
+	return nil, fmt.Errorf("resolving %s: %w", host, errors.Join(errs...))
+}
+
+func (r *Resolver) lookupWith(ctx context.Context, src source, host string) ([]string, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	addrs, err := src.lookup(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = errors.New("no addresses")
+	}
+	return addrs, err
+}
+
+func (r *Resolver) store(host string, addrs []string) {
+	if r.ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cache[host] = cacheEntry{addrs: addrs, expires: r.now().Add(r.ttl)}
+}
+
+// DialContext returns a dial function for http.Transport that resolves the
+// host with r and tries each address in turn with d.
+func (r *Resolver) DialContext(d *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := r.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var dialErr error
+		for _, addr := range addrs {
+			conn, err := d.DialContext(ctx, network, net.JoinHostPort(addr, port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+}
+
+// dnsLookup resolves through the plain DNS server at server.
+func dnsLookup(server string) func(ctx context.Context, host string) ([]string, error) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	return resolver.LookupHost
+}
+
+// dohLookup resolves through the DNS-over-HTTPS endpoint at url, asking for
+// both A and AAAA records.
+func dohLookup(url string, client *http.Client) func(ctx context.Context, host string) ([]string, error) {
+	return func(ctx context.Context, host string) ([]string, error) {
+		var addrs []string
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			found, err := dohQuery(ctx, client, url, host, qtype)
+			if err != nil {
+				return nil, err
+			}
+			addrs = append(addrs, found...)
+		}
+		return addrs, nil
+	}
+}
+
+func dohQuery(ctx context.Context, client *http.Client, url, host string, qtype dnsmessage.Type) ([]string, error) {
+	name, err := dnsmessage.NewName(dnsFQDN(host))
+	if err != nil {
+		return nil, err
+	}
+
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	body, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+
+	var answer dnsmessage.Message
+	if err := answer.Unpack(data); err != nil {
+		return nil, err
+	}
+	if answer.RCode != dnsmessage.RCodeSuccess {
+		return nil, fmt.Errorf("rcode %v", answer.RCode)
+	}
+
+	var addrs []string
+	for _, rr := range answer.Answers {
+		switch rb := rr.Body.(type) {
+		case *dnsmessage.AResource:
+			addrs = append(addrs, net.IP(rb.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			addrs = append(addrs, net.IP(rb.AAAA[:]).String())
+		}
+	}
+	return addrs, nil
+}
+
+func dnsFQDN(host string) string {
+	if len(host) > 0 && host[len(host)-1] == '.' {
+		return host
+	}
+	return host + "."
+}
@@ -0,0 +1,158 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// newFakeDoH starts a DNS-over-HTTPS endpoint that answers A queries from
+// hosts, keyed by fully qualified name, and NXDOMAIN for anything else.
+func newFakeDoH(t *testing.T, hosts map[string][4]byte) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		var query dnsmessage.Message
+		if err := query.Unpack(body); err != nil || len(query.Questions) != 1 {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		q := query.Questions[0]
+
+		answer := dnsmessage.Message{
+			Header:    dnsmessage.Header{ID: query.ID, Response: true},
+			Questions: query.Questions,
+		}
+		ip, ok := hosts[q.Name.String()]
+		switch {
+		case !ok:
+			answer.RCode = dnsmessage.RCodeNameError
+		case q.Type == dnsmessage.TypeA:
+			answer.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: ip},
+			}}
+		}
+
+		packed, err := answer.Pack()
+		if err != nil {
+			t.Errorf("packing answer: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestResolverFallsBackToDoH(t *testing.T) {
+	doh := newFakeDoH(t, map[string][4]byte{"site.test.": {127, 0, 0, 1}})
+
+	r, err := NewResolver(ResolverConfig{
+		// Nothing listens on port 1, so the plain DNS lookup fails fast.
+		Servers: []string{"127.0.0.1:1"},
+		DoHURL:  doh.URL,
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewResolver: %v", err)
+	}
+
+	addrs, err := r.LookupHost(context.Background(), "site.test")
+	if err != nil {
+		t.Fatalf("LookupHost: %v", err)
+	}
+	if len(addrs) != 1 || addrs[0] != "127.0.0.1" {
+		t.Errorf("addrs = %v, want [127.0.0.1]", addrs)
+	}
+
+	if _, err := r.LookupHost(context.Background(), "missing.test"); err == nil {
+		t.Error("LookupHost resolved a name no source knows")
+	}
+}
+
+func TestResolverCachesAnswers(t *testing.T) {
+	calls := 0
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &Resolver{
+		sources: []source{
+			{name: "failing", lookup: func(context.Context, string) ([]string, error) {
+				return nil, errors.New("unreachable")
+			}},
+			{name: "working", lookup: func(context.Context, string) ([]string, error) {
+				calls++
+				return []string{"10.0.0.1"}, nil
+			}},
+		},
+		ttl:   time.Minute,
+		now:   func() time.Time { return now },
+		cache: make(map[string]cacheEntry),
+	}
+
+	for range 3 {
+		if _, err := r.LookupHost(context.Background(), "site.test"); err != nil {
+			t.Fatalf("LookupHost: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("looked up %d times within the TTL, want 1", calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := r.LookupHost(context.Background(), "site.test"); err != nil {
+		t.Fatalf("LookupHost: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("looked up %d times after the TTL, want 2", calls)
+	}
+}
+
+func TestNewRequiresAResolver(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Resolver = ResolverConfig{}
+
+	if _, err := New(cfg); err == nil {
+		t.Error("New accepted a config without any resolver")
+	}
+}
+
+func TestClientDialsResolvedAddress(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(site.Close)
+	doh := newFakeDoH(t, map[string][4]byte{"site.test.": {127, 0, 0, 1}})
+
+	cfg := DefaultConfig()
+	cfg.Resolver = ResolverConfig{DoHURL: doh.URL, Timeout: time.Second}
+	client, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	u, _ := url.Parse(site.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	resp, err := client.Get("http://site.test:" + port + "/")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ok" {
+		t.Errorf("body = %q, want ok", body)
+	}
+}
@@ -0,0 +1,156 @@
+// Package linkcheck probes the player embeds of stored items and records
+// whether they still load.
+package linkcheck
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+)
+
+type (
+	Status    = models.LinkStatus
+	VideoLink = models.VideoLink
+)
+
+// userAgent is sent with every probe; several embed hosts refuse Go's
+// default one.
+const userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+type Options struct {
+	// Concurrency is how many embed URLs are probed at once.
+	Concurrency int
+	// Store is where items are read from and results written to. Nil means
+	// MongoDB through the models package.
+	Store Store
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Concurrency: 8,
+	}
+}
+
+// Summary counts the outcomes of one Check call by availability.
+type Summary map[string]int
+
+// Check probes the VideoURL of every item in the given collections, at most
+// opts.Concurrency at a time, and records the outcome on each item.
+func Check(client *http.Client, collections []string, opts Options) (Summary, error) {
+	store := opts.store()
+	summary := make(Summary)
+
+	for _, collection := range collections {
+		links, err := store.VideoLinks(collection)
+		if err != nil {
+			return summary, fmt.Errorf("failed to load %s video links: %w", collection, err)
+		}
+
+		log.Printf("Checking %d %s video URL(s)", len(links), collection)
+
+		var (
+			mu   sync.Mutex
+			wg   sync.WaitGroup
+			sema = make(chan struct{}, max(opts.Concurrency, 1))
+		)
+		for _, link := range links {
+			sema <- struct{}{}
+			wg.Add(1)
+
+			go func(link VideoLink) {
+				defer func() {
+					<-sema
+					wg.Done()
+				}()
+
+				status := probe(client, link.VideoURL)
+				if status.Availability != models.Available {
+					log.Printf("%s is %s: %s", link.VideoURL, status.Availability, status.Reason)
+				}
+				if err := store.SetStatus(collection, link.Link, status); err != nil {
+					log.Printf("Failed to record link status of %s: %v", link.Link, err)
+				}
+
+				mu.Lock()
+				summary[status.Availability]++
+				mu.Unlock()
+			}(link)
+		}
+		wg.Wait()
+	}
+
+	return summary, nil
+}
+
+// RunEvery runs Check every interval, forever. It is meant to be started in
+// its own goroutine next to the API server.
+func RunEvery(interval time.Duration, client *http.Client, collections []string, opts Options) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		summary, err := Check(client, collections, opts)
+		if err != nil {
+			log.Printf("Link check failed: %v", err)
+			continue
+		}
+		log.Printf("Link check done: %v", summary)
+	}
+}
+
+// probe requests an embed URL and classifies the answer. Missing pages and
+// hosts that no longer resolve make an embed unavailable. Anything else that
+// goes wrong, such as a rate limit, a refusal, a server error or a timeout,
+// says nothing about the embed itself and leaves it unknown.
+func probe(client *http.Client, videoURL string) Status {
+	status := Status{CheckedAt: time.Now()}
+
+	if videoURL == "" {
+		status.Availability = models.Unavailable
+		status.Reason = "no video URL"
+		return status
+	}
+
+	req, err := http.NewRequest(http.MethodGet, videoURL, nil)
+	if err != nil {
+		status.Availability = models.Unavailable
+		status.Reason = err.Error()
+		return status
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			status.Availability = models.Unavailable
+		} else {
+			status.Availability = models.AvailabilityUnknown
+		}
+		status.Reason = err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode < 400:
+		status.Availability = models.Available
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		status.Availability = models.Unavailable
+		status.Reason = fmt.Sprintf("status %d", resp.StatusCode)
+	default:
+		status.Availability = models.AvailabilityUnknown
+		status.Reason = fmt.Sprintf("status %d", resp.StatusCode)
+	}
+
+	return status
+}
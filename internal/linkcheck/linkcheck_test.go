@@ -0,0 +1,99 @@
+package linkcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+)
+
+// memStore is a Store backed by maps, keyed by collection name.
+type memStore struct {
+	mu     sync.Mutex
+	links  map[string][]VideoLink
+	status map[string]Status
+}
+
+func (m *memStore) VideoLinks(collection string) ([]VideoLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.links[collection], nil
+}
+
+func (m *memStore) SetStatus(collection, link string, status Status) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.status == nil {
+		m.status = make(map[string]Status)
+	}
+	m.status[collection+" "+link] = status
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	var agents sync.Map
+	embeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents.Store(r.URL.Path, r.UserAgent())
+
+		switch r.URL.Path {
+		case "/embed/ok":
+			w.Write([]byte("<html>player</html>"))
+		case "/embed/busy":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer embeds.Close()
+
+	store := &memStore{links: map[string][]VideoLink{
+		"movies": {
+			{Link: "/m/ok", VideoURL: embeds.URL + "/embed/ok"},
+			{Link: "/m/gone", VideoURL: embeds.URL + "/embed/gone"},
+			{Link: "/m/none"},
+		},
+		"shows": {
+			{Link: "/s/busy", VideoURL: embeds.URL + "/embed/busy"},
+		},
+	}}
+	opts := DefaultOptions()
+	opts.Concurrency = 2
+	opts.Store = store
+
+	summary, err := Check(embeds.Client(), []string{"movies", "shows"}, opts)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+
+	want := map[string]struct{ availability, reason string }{
+		"movies /m/ok":   {models.Available, ""},
+		"movies /m/gone": {models.Unavailable, "status 404"},
+		"movies /m/none": {models.Unavailable, "no video URL"},
+		"shows /s/busy":  {models.AvailabilityUnknown, "status 503"},
+	}
+	for key, w := range want {
+		got, ok := store.status[key]
+		if !ok {
+			t.Errorf("%s: no status recorded", key)
+			continue
+		}
+		if got.Availability != w.availability || got.Reason != w.reason {
+			t.Errorf("%s: got %q (%q), want %q (%q)", key, got.Availability, got.Reason, w.availability, w.reason)
+		}
+		if got.CheckedAt.IsZero() {
+			t.Errorf("%s: CheckedAt not set", key)
+		}
+	}
+
+	if summary[models.Available] != 1 || summary[models.Unavailable] != 2 || summary[models.AvailabilityUnknown] != 1 {
+		t.Errorf("summary = %v", summary)
+	}
+
+	if ua, _ := agents.Load("/embed/ok"); ua != userAgent {
+		t.Errorf("probe User-Agent = %q, want %q", ua, userAgent)
+	}
+}
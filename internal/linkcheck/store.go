@@ -0,0 +1,27 @@
+package linkcheck
+
+import "github.com/Ka10ken1/mykadri-scraper/internal/models"
+
+// Store is everything the link checker reads from and writes to persistent
+// storage.
+type Store interface {
+	VideoLinks(collection string) ([]VideoLink, error)
+	SetStatus(collection, link string, status Status) error
+}
+
+type mongoStore struct{}
+
+func (mongoStore) VideoLinks(collection string) ([]VideoLink, error) {
+	return models.GetAllVideoLinks(collection)
+}
+
+func (mongoStore) SetStatus(collection, link string, status Status) error {
+	return models.SetLinkStatus(collection, link, status)
+}
+
+func (o Options) store() Store {
+	if o.Store != nil {
+		return o.Store
+	}
+	return mongoStore{}
+}
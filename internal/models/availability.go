@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Availability values recorded by the link checker. Items that were never
+// checked have none.
+const (
+	Available   = "available"
+	Unavailable = "unavailable"
+	// AvailabilityUnknown means the last probe failed in a way that says
+	// nothing about the embed itself, such as a timeout or a 5xx.
+	AvailabilityUnknown = "unknown"
+)
+
+// LinkStatus is the outcome of probing an item's VideoURL.
+type LinkStatus struct {
+	Availability string
+	// Reason says why the probe did not find the embed available.
+	Reason    string
+	CheckedAt time.Time
+}
+
+// VideoLink identifies an item and the embed URL its player loads.
+type VideoLink struct {
+	Link     string `bson:"link"`
+	VideoURL string `bson:"videoUrl"`
+}
+
+func GetAllVideoLinks(collectionName string) ([]VideoLink, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"link": 1, "videoUrl": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []VideoLink
+	for cursor.Next(ctx) {
+		var l VideoLink
+		if err := cursor.Decode(&l); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+
+	return links, cursor.Err()
+}
+
+// SetLinkStatus records the result of a link check on the item stored
+// under link.
+func SetLinkStatus(collectionName, link string, status LinkStatus) error {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		"availability":  status.Availability,
+		"lastCheckedAt": status.CheckedAt,
+	}
+	update := bson.M{"$set": set}
+	if status.Reason != "" {
+		set["failureReason"] = status.Reason
+	} else {
+		update["$unset"] = bson.M{"failureReason": ""}
+	}
+
+	_, err = coll.UpdateMany(ctx, bson.M{"link": link}, update)
+	return err
+}
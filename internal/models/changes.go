@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/translit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const changesCollection = "changes"
+
+// Change records one field of a stored item that a re-scrape found changed
+// on the site.
+type Change struct {
+	Collection string `bson:"collection"`
+	Link       string `bson:"link"`
+	// Field is the bson name of the changed Item field.
+	Field     string    `bson:"field"`
+	Old       any       `bson:"old"`
+	New       any       `bson:"new"`
+	ChangedAt time.Time `bson:"changedAt"`
+}
+
+func GetItemByLink(collectionName, link string) (*Item, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var it Item
+	err = coll.FindOne(ctx, bson.M{"link": link}).Decode(&it)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &it, nil
+}
+
+// UpdateItem sets the new value of every change on the item stored under
+// link and appends the changes to the changes collection. A change of
+// delisted to false unsets delisted and delistedAt.
+func UpdateItem(collectionName, link string, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+	history, err := collection(changesCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	set, unset := bson.M{}, bson.M{}
+	docs := make([]any, 0, len(changes))
+	for _, ch := range changes {
+		docs = append(docs, ch)
+
+		// A relisted item loses both delisted fields, as the omitempty tags
+		// of Item would have it.
+		if relisted, ok := ch.New.(bool); ok && ch.Field == "delisted" && !relisted {
+			unset["delisted"] = ""
+			unset["delistedAt"] = ""
+			continue
+		}
+		set[ch.Field] = ch.New
+
+		if title, ok := ch.New.(string); ok && ch.Field == "title" {
+			set["titleLatin"] = translit.SearchKey(title)
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if _, err := coll.UpdateOne(ctx, bson.M{"link": link}, update); err != nil {
+		return err
+	}
+
+	_, err = history.InsertMany(ctx, docs)
+	return err
+}
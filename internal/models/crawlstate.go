@@ -0,0 +1,78 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const crawlStateCollection = "crawl_state"
+
+// CrawlState is the checkpoint of an unfinished crawl of one category. It is
+// deleted once the crawl completes.
+type CrawlState struct {
+	Category string `bson:"_id"`
+	Mode     string `bson:"mode"`
+	// LastPage is the number of listing pages the crawl set out to visit.
+	LastPage       int   `bson:"lastPage"`
+	CompletedPages []int `bson:"completedPages"`
+	// PendingDetails are listing entries whose detail page had not been
+	// fetched and stored yet.
+	PendingDetails []Item    `bson:"pendingDetails"`
+	StartedAt      time.Time `bson:"startedAt"`
+	UpdatedAt      time.Time `bson:"updatedAt"`
+}
+
+// LoadCrawlState returns the checkpoint for category, or nil if there is
+// none.
+func LoadCrawlState(category string) (*CrawlState, error) {
+	coll, err := collection(crawlStateCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var state CrawlState
+	err = coll.FindOne(ctx, bson.M{"_id": category}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func SaveCrawlState(state *CrawlState) error {
+	coll, err := collection(crawlStateCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	state.UpdatedAt = time.Now()
+	_, err = coll.ReplaceOne(ctx, bson.M{"_id": state.Category}, state, options.Replace().SetUpsert(true))
+	return err
+}
+
+func DeleteCrawlState(category string) error {
+	coll, err := collection(crawlStateCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = coll.DeleteOne(ctx, bson.M{"_id": category})
+	return err
+}
@@ -0,0 +1,243 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DedupeCandidate is what the duplicate finder compares of an item.
+type DedupeCandidate struct {
+	ID           primitive.ObjectID `bson:"_id" json:"id"`
+	Link         string             `bson:"link" json:"link"`
+	Title        string             `bson:"title" json:"title"`
+	TitleEnglish string             `bson:"titleEnglish" json:"titleEnglish"`
+	Year         string             `bson:"year" json:"year"`
+	ImdbID       string             `bson:"imdbId,omitempty" json:"imdbId,omitempty"`
+	ImagePHash   string             `bson:"imagePHash,omitempty" json:"imagePHash,omitempty"`
+	Sources      []VideoSource      `bson:"sources,omitempty" json:"-"`
+}
+
+func GetDedupeCandidates(collectionName string) ([]DedupeCandidate, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	projection := bson.M{
+		"link": 1, "title": 1, "titleEnglish": 1, "year": 1,
+		"imdbId": 1, "imagePHash": 1, "sources": 1,
+	}
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var candidates []DedupeCandidate
+	for cursor.Next(ctx) {
+		var c DedupeCandidate
+		if err := cursor.Decode(&c); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+
+	return candidates, cursor.Err()
+}
+
+// MergeItems folds the items stored under aliasLinks into the one stored
+// under canonicalLink: their links, aliases, player sources and seasons are
+// added to it, the fields it is missing are taken from them, and they are
+// deleted. The change of aliases and every deleted document are recorded in
+// the changes collection, so a merge can be undone by hand. The canonical
+// item is updated before the aliases are deleted, so a failure part way
+// leaves duplicates behind rather than losing data.
+func MergeItems(collectionName, canonicalLink string, aliasLinks []string) error {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var canonical Item
+	err = coll.FindOne(ctx, bson.M{"link": canonicalLink}).Decode(&canonical)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("no item stored under %s", canonicalLink)
+	}
+	if err != nil {
+		return err
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{"link": bson.M{"$in": aliasLinks}})
+	if err != nil {
+		return err
+	}
+	var raw []bson.M
+	if err := cursor.All(ctx, &raw); err != nil {
+		return err
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+
+	set := bson.M{}
+	merged := slices.Clone(canonical.Aliases)
+	for _, doc := range raw {
+		var a Item
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		if err := bson.Unmarshal(data, &a); err != nil {
+			return err
+		}
+
+		for _, link := range append([]string{a.Link}, a.Aliases...) {
+			if link != canonical.Link && !slices.Contains(merged, link) {
+				merged = append(merged, link)
+			}
+		}
+		for k, v := range fillMissing(&canonical, a) {
+			set[k] = v
+		}
+	}
+	set["aliases"] = merged
+
+	if _, err := coll.UpdateOne(ctx, bson.M{"link": canonical.Link}, bson.M{"$set": set}); err != nil {
+		return err
+	}
+
+	history, err := collection(changesCollection)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	records := []any{Change{
+		Collection: collectionName,
+		Link:       canonical.Link,
+		Field:      "aliases",
+		Old:        canonical.Aliases,
+		New:        merged,
+		ChangedAt:  now,
+	}}
+	linksToDelete := make([]string, 0, len(raw))
+	for _, doc := range raw {
+		link, _ := doc["link"].(string)
+		linksToDelete = append(linksToDelete, link)
+		// The whole deleted document, so it can be put back.
+		records = append(records, Change{
+			Collection: collectionName,
+			Link:       link,
+			Field:      "mergedInto",
+			Old:        doc,
+			New:        canonical.Link,
+			ChangedAt:  now,
+		})
+	}
+	if _, err := history.InsertMany(ctx, records); err != nil {
+		return err
+	}
+
+	_, err = coll.DeleteMany(ctx, bson.M{"link": bson.M{"$in": linksToDelete}})
+	return err
+}
+
+// fillMissing adds what dup has and dst lacks to dst: player sources,
+// seasons and episodes, and every detail field dst has left empty. It
+// returns the bson fields of dst that changed.
+func fillMissing(dst *Item, dup Item) bson.M {
+	set := bson.M{}
+	fillString := func(field string, d *string, s string) {
+		if *d == "" && s != "" {
+			*d = s
+			set[field] = s
+		}
+	}
+	fillStrings := func(field string, d *[]string, s []string) {
+		if len(*d) == 0 && len(s) > 0 {
+			*d = s
+			set[field] = s
+		}
+	}
+
+	fillString("titleEnglish", &dst.TitleEnglish, dup.TitleEnglish)
+	fillString("year", &dst.Year, dup.Year)
+	fillString("videoUrl", &dst.VideoURL, dup.VideoURL)
+	fillString("imdbId", &dst.ImdbID, dup.ImdbID)
+	fillString("description", &dst.Description, dup.Description)
+	fillString("country", &dst.Country, dup.Country)
+	fillString("director", &dst.Director, dup.Director)
+	fillStrings("genres", &dst.Genres, dup.Genres)
+	fillStrings("actors", &dst.Actors, dup.Actors)
+	if dst.Rating == 0 && dup.Rating != 0 {
+		dst.Rating = dup.Rating
+		set["rating"] = dup.Rating
+	}
+	if dst.Runtime == 0 && dup.Runtime != 0 {
+		dst.Runtime = dup.Runtime
+		set["runtime"] = dup.Runtime
+	}
+
+	// The poster fields belong together, so they are only taken as a whole.
+	if dst.Image == "" && dup.Image != "" {
+		fillString("image", &dst.Image, dup.Image)
+		fillString("imageHash", &dst.ImageHash, dup.ImageHash)
+		fillString("imageSource", &dst.ImageSource, dup.ImageSource)
+		fillString("imageBlurHash", &dst.ImageBlurHash, dup.ImageBlurHash)
+		fillString("imageColor", &dst.ImageColor, dup.ImageColor)
+		fillString("imagePHash", &dst.ImagePHash, dup.ImagePHash)
+	}
+
+	for _, src := range dup.Sources {
+		if !slices.Contains(dst.Sources, src) {
+			dst.Sources = append(dst.Sources, src)
+			set["sources"] = dst.Sources
+		}
+	}
+
+	if seasons, changed := mergeSeasons(dst.Seasons, dup.Seasons); changed {
+		dst.Seasons = seasons
+		set["seasons"] = seasons
+	}
+	return set
+}
+
+// mergeSeasons adds the seasons and episodes of b that a does not have, by
+// number, and reports whether any were added. Seasons and episodes are kept
+// in order.
+func mergeSeasons(a, b []Season) ([]Season, bool) {
+	out := slices.Clone(a)
+	changed := false
+	for _, sb := range b {
+		i := slices.IndexFunc(out, func(s Season) bool { return s.Number == sb.Number })
+		if i < 0 {
+			out = append(out, sb)
+			changed = true
+			continue
+		}
+		episodes := slices.Clone(out[i].Episodes)
+		for _, eb := range sb.Episodes {
+			if !slices.ContainsFunc(episodes, func(e Episode) bool { return e.Number == eb.Number }) {
+				episodes = append(episodes, eb)
+				changed = true
+			}
+		}
+		slices.SortFunc(episodes, func(x, y Episode) int { return x.Number - y.Number })
+		out[i].Episodes = episodes
+	}
+	slices.SortFunc(out, func(x, y Season) int { return x.Number - y.Number })
+	return out, changed
+}
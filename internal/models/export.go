@@ -0,0 +1,92 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExportItems writes every document of the collection to w as one line of
+// relaxed extended JSON each, so ObjectIDs and dates survive ImportItems.
+func ExportItems(collectionName string, w io.Writer) (int, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	bw := bufio.NewWriter(w)
+	n := 0
+	for cursor.Next(ctx) {
+		line, err := bson.MarshalExtJSON(cursor.Current, false, false)
+		if err != nil {
+			return n, err
+		}
+		if _, err := bw.Write(append(line, '\n')); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := cursor.Err(); err != nil {
+		return n, err
+	}
+
+	return n, bw.Flush()
+}
+
+// ImportItems reads documents written by ExportItems from r into the
+// collection, replacing documents with the same _id.
+func ImportItems(collectionName string, r io.Reader) (int, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	n := 0
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), false, &doc); err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var id any
+		for _, e := range doc {
+			if e.Key == "_id" {
+				id = e.Value
+				break
+			}
+		}
+
+		if id == nil {
+			_, err = coll.InsertOne(ctx, doc)
+		} else {
+			_, err = coll.ReplaceOne(ctx, bson.M{"_id": id}, doc, options.Replace().SetUpsert(true))
+		}
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", line, err)
+		}
+		n++
+	}
+
+	return n, scanner.Err()
+}
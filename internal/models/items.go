@@ -0,0 +1,335 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/translit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Item is the document stored for every scraped title, whatever category of
+// mykadri.tv it was found in.
+type Item struct {
+	Title        string `bson:"title"`
+	TitleEnglish string `bson:"titleEnglish"`
+	Year         string `bson:"year"`
+	Link         string `bson:"link"`
+	Image        string `bson:"image"`
+	VideoURL     string `bson:"videoUrl"`
+	ImdbID       string `bson:"imdbId,omitempty"`
+	// TitleLatin is the search key of Title in Latin letters; see
+	// translit.SearchKey. It is set when the item is inserted.
+	TitleLatin string `bson:"titleLatin,omitempty"`
+	// Sources lists every player found on the detail page. VideoURL is the
+	// preferred one among them.
+	Sources []VideoSource `bson:"sources,omitempty"`
+
+	Description string   `bson:"description,omitempty"`
+	Genres      []string `bson:"genres,omitempty"`
+	Rating      float64  `bson:"rating,omitempty"`
+	// Runtime is the running time in minutes.
+	Runtime  int      `bson:"runtime,omitempty"`
+	Country  string   `bson:"country,omitempty"`
+	Director string   `bson:"director,omitempty"`
+	Actors   []string `bson:"actors,omitempty"`
+
+	// Seasons is only filled in for shows.
+	Seasons []Season `bson:"seasons,omitempty"`
+
+	// Availability, LastCheckedAt and FailureReason are set by the link
+	// checker; see LinkStatus.
+	Availability  string    `bson:"availability,omitempty"`
+	LastCheckedAt time.Time `bson:"lastCheckedAt,omitempty"`
+	FailureReason string    `bson:"failureReason,omitempty"`
+
+	// Delisted is set once a complete crawl no longer finds the item on
+	// mykadri.tv.
+	Delisted   bool      `bson:"delisted,omitempty"`
+	DelistedAt time.Time `bson:"delistedAt,omitempty"`
+
+	// ImageHash names the mirrored copy of Image, downloaded from
+	// ImageSource. A poster that changes since gets mirrored again.
+	ImageHash   string `bson:"imageHash,omitempty"`
+	ImageSource string `bson:"imageSource,omitempty"`
+	// ImageBlurHash and ImageColor are placeholders for the poster; see
+	// PosterInfo.
+	ImageBlurHash string `bson:"imageBlurHash,omitempty"`
+	ImageColor    string `bson:"imageColor,omitempty"`
+	ImagePHash    string `bson:"imagePHash,omitempty"`
+
+	// Aliases are the links of duplicate documents merged into this one;
+	// see MergeItems.
+	Aliases []string `bson:"aliases,omitempty"`
+}
+
+type VideoSource struct {
+	Provider string `bson:"provider"`
+	URL      string `bson:"url"`
+	Language string `bson:"language,omitempty"`
+}
+
+type Season struct {
+	Number   int       `bson:"number"`
+	Episodes []Episode `bson:"episodes"`
+}
+
+type Episode struct {
+	Number   int    `bson:"number"`
+	Title    string `bson:"title"`
+	VideoURL string `bson:"videoUrl"`
+}
+
+var database *mongo.Database
+
+func collection(name string) (*mongo.Collection, error) {
+	if database == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+	return database.Collection(name), nil
+}
+
+func InsertItems(collectionName string, items []Item) error {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var docs []any
+	for _, it := range items {
+		if it.TitleLatin == "" {
+			it.TitleLatin = translit.SearchKey(it.Title)
+		}
+		log.Printf("Inserting into %s: %+v\n", collectionName, it)
+		docs = append(docs, it)
+	}
+
+	_, err = coll.InsertMany(ctx, docs)
+	return err
+}
+
+// EnsureItemIndexes creates the lookup indexes every item collection needs.
+func EnsureItemIndexes(collectionName string) error {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err = coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "link", Value: 1}}},
+		{Keys: bson.D{{Key: "imdbId", Value: 1}}, Options: options.Index().SetSparse(true)},
+	})
+	return err
+}
+
+// BackfillImdbIDs sets imdbId on documents stored before it had its own
+// field, taking it from the imdb= parameter of videoUrl.
+func BackfillImdbIDs(collectionName string) error {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"imdbId":   bson.M{"$exists": false},
+		"videoUrl": bson.M{"$regex": `imdb=tt\d+`},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"imdbId": bson.M{"$arrayElemAt": bson.A{
+				bson.M{"$getField": bson.M{
+					"field": "captures",
+					"input": bson.M{"$regexFind": bson.M{"input": "$videoUrl", "regex": `imdb=(tt\d+)`}},
+				}},
+				0,
+			}},
+		}}},
+	}
+
+	res, err := coll.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount > 0 {
+		log.Printf("Backfilled imdbId on %d %s documents", res.ModifiedCount, collectionName)
+	}
+	return nil
+}
+
+// BackfillTitleLatin sets titleLatin on documents inserted before it
+// existed or brought in by ImportItems.
+func BackfillTitleLatin(collectionName string) error {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter := bson.M{"titleLatin": bson.M{"$exists": false}, "title": bson.M{"$nin": bson.A{"", nil}}}
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"title": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var updates []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var it struct {
+			ID    any    `bson:"_id"`
+			Title string `bson:"title"`
+		}
+		if err := cursor.Decode(&it); err != nil {
+			return err
+		}
+		updates = append(updates, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": it.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"titleLatin": translit.SearchKey(it.Title)}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	res, err := coll.BulkWrite(ctx, updates)
+	if err != nil {
+		return err
+	}
+	log.Printf("Backfilled titleLatin on %d %s documents", res.ModifiedCount, collectionName)
+	return nil
+}
+
+func GetAllImdbIDs(collectionName string) ([]string, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"imdbId": bson.M{"$exists": true, "$ne": ""}}
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"imdbId": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []string
+	for cursor.Next(ctx) {
+		var it struct {
+			ImdbID string `bson:"imdbId"`
+		}
+		if err := cursor.Decode(&it); err != nil {
+			return nil, err
+		}
+		ids = append(ids, it.ImdbID)
+	}
+
+	return ids, nil
+}
+
+func getByImdbID(coll *mongo.Collection, imdbID string) (*Item, error) {
+	if coll == nil {
+		return nil, mongo.ErrClientDisconnected
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var it Item
+	err := coll.FindOne(ctx, bson.M{"imdbId": imdbID}).Decode(&it)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &it, nil
+}
+
+// GetAllLinks returns the link of every item in the collection along with
+// the aliases merged into it, so that merged duplicates are not scraped
+// again.
+func GetAllLinks(collectionName string) ([]string, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"link": 1, "aliases": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []string
+	for cursor.Next(ctx) {
+		var it struct {
+			Link    string   `bson:"link"`
+			Aliases []string `bson:"aliases"`
+		}
+		if err := cursor.Decode(&it); err != nil {
+			return nil, err
+		}
+		links = append(links, it.Link)
+		links = append(links, it.Aliases...)
+	}
+
+	return links, nil
+}
+
+// MarkDelisted flags every item in the collection whose link is not in
+// listed, under neither its own link nor an alias, as delisted at the given
+// time, and clears the flag on listed items that had it. It returns how
+// many items were newly delisted.
+func MarkDelisted(collectionName string, listed []string, at time.Time) (int64, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if listed == nil {
+		listed = []string{}
+	}
+
+	res, err := coll.UpdateMany(ctx,
+		bson.M{"link": bson.M{"$nin": listed}, "aliases": bson.M{"$nin": listed}, "delisted": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"delisted": true, "delistedAt": at}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = coll.UpdateMany(ctx,
+		bson.M{
+			"$or":      bson.A{bson.M{"link": bson.M{"$in": listed}}, bson.M{"aliases": bson.M{"$in": listed}}},
+			"delisted": true,
+		},
+		bson.M{"$unset": bson.M{"delisted": "", "delistedAt": ""}},
+	)
+	return res.ModifiedCount, err
+}
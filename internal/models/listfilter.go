@@ -0,0 +1,24 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// ListFilter narrows down the items returned by the list queries.
+type ListFilter struct {
+	// OnlyAvailable leaves out items whose embed the link checker found
+	// dead. Items that were never checked or whose last check was
+	// inconclusive are kept.
+	OnlyAvailable bool
+	// IncludeDelisted keeps items that are no longer listed on mykadri.tv.
+	IncludeDelisted bool
+}
+
+func (f ListFilter) bson() bson.M {
+	filter := bson.M{}
+	if f.OnlyAvailable {
+		filter["availability"] = bson.M{"$ne": Unavailable}
+	}
+	if !f.IncludeDelisted {
+		filter["delisted"] = bson.M{"$ne": true}
+	}
+	return filter
+}
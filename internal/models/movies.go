@@ -6,6 +6,7 @@ import (
     "regexp"
     "time"
 
+    "github.com/Ka10ken1/mykadri-scraper/internal/translit"
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
     "go.mongodb.org/mongo-driver/mongo"
@@ -13,14 +14,7 @@ import (
 )
 
 
-type Movie struct {
-    Title        string `bson:"title"`
-    TitleEnglish string `bson:"titleEnglish"`
-    Year         string `bson:"year"`
-    Link         string `bson:"link"`     
-    Image        string `bson:"image"`
-    VideoURL     string `bson:"videoUrl"` 
-}
+type Movie = Item
 
 
 type MovieImage struct {
@@ -28,6 +22,9 @@ type MovieImage struct {
     Title string             `bson:"title"`
     TitleEnglish string      `bsin:"titleEnglish"`
     Image string             `bson:"image" json:"image"`
+    ImageHash string         `bson:"imageHash,omitempty" json:"-"`
+    BlurHash string          `bson:"imageBlurHash,omitempty" json:"blurhash,omitempty"`
+    Color string             `bson:"imageColor,omitempty" json:"color,omitempty"`
 }
 
 
@@ -48,7 +45,8 @@ func InitMongo(uri, dbName, collectionName string) error {
 
     log.Println("MongoDB Connected")
 
-    movieCollection = client.Database(dbName).Collection(collectionName)
+    database = client.Database(dbName)
+    movieCollection = database.Collection(collectionName)
 
     return nil
 }
@@ -73,7 +71,8 @@ func InsertMovies(movies []Movie) error {
 }
 
 
-func GetAllMovies() ([]Movie, error) {
+// GetAllMovies returns the stored movies that match filter.
+func GetAllMovies(filter ListFilter) ([]Movie, error) {
     if movieCollection == nil {
 	return nil, mongo.ErrClientDisconnected
     }
@@ -81,7 +80,7 @@ func GetAllMovies() ([]Movie, error) {
     ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Second)
     defer cancel()
 
-    cursor, err := movieCollection.Find(ctx, bson.M{})
+    cursor, err := movieCollection.Find(ctx, filter.bson())
     if err != nil {
 	return nil, err
     }
@@ -103,7 +102,7 @@ func GetAllMovies() ([]Movie, error) {
     return movies, nil
 }
 
-func GetAllMovieImages() ([]MovieImage, error) {
+func GetAllMovieImages(filter ListFilter) ([]MovieImage, error) {
     if movieCollection == nil {
 	return nil, mongo.ErrClientDisconnected
     }
@@ -115,9 +114,12 @@ func GetAllMovieImages() ([]MovieImage, error) {
 	"image": 1,
 	"title": 1,
 	"titleEnglish" : 1,
+	"imageHash": 1,
+	"imageBlurHash": 1,
+	"imageColor": 1,
     })
 
-    cursor, err := movieCollection.Find(ctx, bson.M{}, opts)
+    cursor, err := movieCollection.Find(ctx, filter.bson(), opts)
     if err != nil {
 	return nil, err
     }
@@ -158,6 +160,10 @@ func GetMovieByID(idStr string) (*Movie, error) {
     return &movie, nil
 }
 
+func GetMovieByImdbID(imdbID string) (*Movie, error) {
+    return getByImdbID(movieCollection, imdbID)
+}
+
 func GetAllMovieLinks() ([]string, error) {
     if movieCollection == nil {
 	return nil, mongo.ErrClientDisconnected
@@ -229,7 +235,7 @@ func RebuildTextIndex() error {
     return err
 }
 
-func SearchMoviesByTitle(query string) ([]Movie, error) {
+func SearchMoviesByTitle(query string, listFilter ListFilter) ([]Movie, error) {
     if movieCollection == nil {
 	return nil, mongo.ErrClientDisconnected
     }
@@ -239,12 +245,17 @@ func SearchMoviesByTitle(query string) ([]Movie, error) {
 
     safeQuery := regexp.QuoteMeta(query)
 
-    filter := bson.M{
-	"$or": []bson.M{
-	    {"title": bson.M{"$regex": safeQuery, "$options": "i"}},
-	    {"titleEnglish": bson.M{"$regex": safeQuery, "$options": "i"}},
-	},
+    filter := listFilter.bson()
+    or := []bson.M{
+	{"title": bson.M{"$regex": safeQuery, "$options": "i"}},
+	{"titleEnglish": bson.M{"$regex": safeQuery, "$options": "i"}},
+    }
+    // A Georgian title typed in Latin letters, or the other way round,
+    // matches through the romanized search key.
+    if key := translit.SearchKey(query); key != "" {
+	or = append(or, bson.M{"titleLatin": bson.M{"$regex": regexp.QuoteMeta(key)}})
     }
+    filter["$or"] = or
 
     cursor, err := movieCollection.Find(ctx, filter)
     if err != nil {
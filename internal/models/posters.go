@@ -0,0 +1,88 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PosterRef identifies an item and the poster URL it was scraped with.
+type PosterRef struct {
+	Link  string `bson:"link"`
+	Image string `bson:"image"`
+}
+
+// PosterInfo is what mirroring a poster records on its item.
+type PosterInfo struct {
+	Hash string
+	// BlurHash and Color are placeholders to paint while the poster loads.
+	// Both are empty for images that could not be decoded.
+	BlurHash string
+	Color    string
+	// PHash is a perceptual hash of the poster, 16 hex digits, that the
+	// duplicate finder compares.
+	PHash string
+}
+
+// GetUnmirroredPosters returns the items whose poster was never mirrored,
+// has changed since it was, or was mirrored before placeholders and
+// perceptual hashes were computed.
+func GetUnmirroredPosters(collectionName string) ([]PosterRef, error) {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"image": bson.M{"$nin": bson.A{"", nil}},
+		"$or": bson.A{
+			bson.M{"$expr": bson.M{"$ne": bson.A{"$image", "$imageSource"}}},
+			bson.M{"imageBlurHash": bson.M{"$exists": false}},
+			bson.M{"imagePHash": bson.M{"$exists": false}},
+		},
+	}
+	cursor, err := coll.Find(ctx, filter, options.Find().SetProjection(bson.M{"link": 1, "image": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var refs []PosterRef
+	for cursor.Next(ctx) {
+		var r PosterRef
+		if err := cursor.Decode(&r); err != nil {
+			return nil, err
+		}
+		refs = append(refs, r)
+	}
+
+	return refs, cursor.Err()
+}
+
+// SetPoster records that the poster at source, stored under link, was
+// mirrored as described by info.
+func SetPoster(collectionName, link, source string, info PosterInfo) error {
+	coll, err := collection(collectionName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// imageBlurHash and imagePHash are set even when empty so that
+	// undecodable posters are not picked up again by GetUnmirroredPosters.
+	_, err = coll.UpdateMany(ctx, bson.M{"link": link}, bson.M{"$set": bson.M{
+		"imageHash":     info.Hash,
+		"imageSource":   source,
+		"imageBlurHash": info.BlurHash,
+		"imageColor":    info.Color,
+		"imagePHash":    info.PHash,
+	}})
+	return err
+}
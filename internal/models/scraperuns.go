@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const scrapeRunsCollection = "scrape_runs"
+
+// ScrapeRun is the record of one scrape of one category. It is written when
+// the run starts and again when it finishes, so a run without FinishedAt
+// either is still going or died.
+type ScrapeRun struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Category   string             `bson:"category" json:"category"`
+	Mode       string             `bson:"mode" json:"mode"`
+	StartedAt  time.Time          `bson:"startedAt" json:"startedAt"`
+	FinishedAt time.Time          `bson:"finishedAt,omitempty" json:"finishedAt,omitzero"`
+	// Error is why the run stopped early, if it did.
+	Error string `bson:"error,omitempty" json:"error,omitempty"`
+
+	PagesVisited int `bson:"pagesVisited" json:"pagesVisited"`
+	// ItemsFound counts the posts on the visited listing pages.
+	ItemsFound int `bson:"itemsFound" json:"itemsFound"`
+	New        int `bson:"new" json:"new"`
+	Updated    int `bson:"updated" json:"updated"`
+	// Skipped counts posts that were already stored and unchanged, or
+	// duplicates of another title.
+	Skipped int `bson:"skipped" json:"skipped"`
+
+	Failures []RunFailure `bson:"failures,omitempty" json:"failures,omitempty"`
+	// StatusCounts is a histogram of the HTTP status codes the run got back,
+	// keyed by code; "0" counts requests that failed without a response.
+	StatusCounts map[string]int `bson:"statusCounts,omitempty" json:"statusCounts,omitempty"`
+	// Proxies holds per-proxy counts when the run went through a proxy pool.
+	Proxies []ProxyStats `bson:"proxies,omitempty" json:"proxies,omitempty"`
+}
+
+// RunFailure is a URL a run gave up on.
+type RunFailure struct {
+	URL      string `bson:"url" json:"url"`
+	Attempts int    `bson:"attempts" json:"attempts"`
+	Status   int    `bson:"status,omitempty" json:"status,omitempty"`
+	Reason   string `bson:"reason" json:"reason"`
+}
+
+// ProxyStats counts the requests a run sent through one proxy.
+type ProxyStats struct {
+	// URL is the proxy URL with any password redacted.
+	URL         string `bson:"url" json:"url"`
+	Requests    int    `bson:"requests" json:"requests"`
+	Failures    int    `bson:"failures" json:"failures"`
+	RateLimited int    `bson:"rateLimited" json:"rateLimited"`
+	// Ejections counts how often the proxy was taken out of rotation.
+	Ejections int `bson:"ejections" json:"ejections"`
+}
+
+// SaveScrapeRun inserts run, assigning it an ID, or replaces it if it has
+// one already.
+func SaveScrapeRun(run *ScrapeRun) error {
+	coll, err := collection(scrapeRunsCollection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if run.ID.IsZero() {
+		run.ID = primitive.NewObjectID()
+	}
+	_, err = coll.ReplaceOne(ctx, bson.M{"_id": run.ID}, run, options.Replace().SetUpsert(true))
+	return err
+}
+
+// GetScrapeRuns returns up to limit runs, newest first, optionally only
+// those of one category.
+func GetScrapeRuns(category string, limit int64) ([]ScrapeRun, error) {
+	coll, err := collection(scrapeRunsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if category != "" {
+		filter["category"] = category
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetLimit(limit)
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	runs := []ScrapeRun{}
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+func GetScrapeRunByID(idStr string) (*ScrapeRun, error) {
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return nil, err
+	}
+
+	coll, err := collection(scrapeRunsCollection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var run ScrapeRun
+	err = coll.FindOne(ctx, bson.M{"_id": id}).Decode(&run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &run, nil
+}
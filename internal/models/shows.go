@@ -2,30 +2,28 @@ package models
 
 import (
 	"context"
+	"errors"
 	"log"
 	"regexp"
 	"time"
 
+	"github.com/Ka10ken1/mykadri-scraper/internal/translit"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type Show struct {
-	Title        string `bson:"title"`
-	TitleEnglish string `bson:"titleEnglish"`
-	Year         string `bson:"year"`
-	Link         string `bson:"link"`
-	Image        string `bson:"image"`
-	VideoURL     string `bson:"videoUrl"`
-}
+type Show = Item
 
 type ShowImage struct {
 	ID           primitive.ObjectID `bson:"_id" json:"id"`
 	Title        string             `bson:"title"`
 	TitleEnglish string             `bson:"titleEnglish"`
 	Image        string             `bson:"image" json:"image"`
+	ImageHash    string             `bson:"imageHash,omitempty" json:"-"`
+	BlurHash     string             `bson:"imageBlurHash,omitempty" json:"blurhash,omitempty"`
+	Color        string             `bson:"imageColor,omitempty" json:"color,omitempty"`
 }
 
 var showCollection *mongo.Collection
@@ -41,6 +39,9 @@ func InitShowMongo(uri, dbName, collectionName string) error {
 	}
 
 	log.Println("MongoDB Connected for shows")
+	if database == nil {
+		database = client.Database(dbName)
+	}
 	showCollection = client.Database(dbName).Collection(collectionName)
 	return nil
 }
@@ -63,7 +64,8 @@ func InsertShows(shows []Show) error {
 	return err
 }
 
-func GetAllShows() ([]Show, error) {
+// GetAllShows returns the stored shows that match filter.
+func GetAllShows(filter ListFilter) ([]Show, error) {
 	if showCollection == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -71,7 +73,7 @@ func GetAllShows() ([]Show, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := showCollection.Find(ctx, bson.M{})
+	cursor, err := showCollection.Find(ctx, filter.bson())
 	if err != nil {
 		return nil, err
 	}
@@ -89,7 +91,7 @@ func GetAllShows() ([]Show, error) {
 	return shows, nil
 }
 
-func GetAllShowImages() ([]ShowImage, error) {
+func GetAllShowImages(filter ListFilter) ([]ShowImage, error) {
 	if showCollection == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -98,12 +100,15 @@ func GetAllShowImages() ([]ShowImage, error) {
 	defer cancel()
 
 	opts := options.Find().SetProjection(bson.M{
-		"image":        1,
-		"title":        1,
-		"titleEnglish": 1,
+		"image":         1,
+		"title":         1,
+		"titleEnglish":  1,
+		"imageHash":     1,
+		"imageBlurHash": 1,
+		"imageColor":    1,
 	})
 
-	cursor, err := showCollection.Find(ctx, bson.M{}, opts)
+	cursor, err := showCollection.Find(ctx, filter.bson(), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -121,6 +126,8 @@ func GetAllShowImages() ([]ShowImage, error) {
 	return images, nil
 }
 
+// GetShowByID returns the show with the hex ObjectID idStr, or nil if there
+// is none or idStr is not an ObjectID.
 func GetShowByID(idStr string) (*Show, error) {
 	if showCollection == nil {
 		return nil, mongo.ErrClientDisconnected
@@ -128,7 +135,7 @@ func GetShowByID(idStr string) (*Show, error) {
 
 	id, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
-		return nil, err
+		return nil, nil
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -136,6 +143,9 @@ func GetShowByID(idStr string) (*Show, error) {
 
 	var show Show
 	err = showCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&show)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -143,6 +153,10 @@ func GetShowByID(idStr string) (*Show, error) {
 	return &show, nil
 }
 
+func GetShowByImdbID(imdbID string) (*Show, error) {
+	return getByImdbID(showCollection, imdbID)
+}
+
 func GetAllShowLinks() ([]string, error) {
 	if showCollection == nil {
 		return nil, mongo.ErrClientDisconnected
@@ -198,7 +212,7 @@ func HasShows() (bool, error) {
 	return count > 0, nil
 }
 
-func SearchShowsByTitle(query string) ([]Show, error) {
+func SearchShowsByTitle(query string, listFilter ListFilter) ([]Show, error) {
 	if showCollection == nil {
 		return nil, mongo.ErrClientDisconnected
 	}
@@ -208,12 +222,17 @@ func SearchShowsByTitle(query string) ([]Show, error) {
 
 	safeQuery := regexp.QuoteMeta(query)
 
-	filter := bson.M{
-		"$or": []bson.M{
-			{"title": bson.M{"$regex": safeQuery, "$options": "i"}},
-			{"titleEnglish": bson.M{"$regex": safeQuery, "$options": "i"}},
-		},
+	filter := listFilter.bson()
+	or := []bson.M{
+		{"title": bson.M{"$regex": safeQuery, "$options": "i"}},
+		{"titleEnglish": bson.M{"$regex": safeQuery, "$options": "i"}},
+	}
+	// A Georgian title typed in Latin letters, or the other way round,
+	// matches through the romanized search key.
+	if key := translit.SearchKey(query); key != "" {
+		or = append(or, bson.M{"titleLatin": bson.M{"$regex": regexp.QuoteMeta(key)}})
 	}
+	filter["$or"] = or
 
 	cursor, err := showCollection.Find(ctx, filter)
 	if err != nil {
@@ -0,0 +1,151 @@
+package posters
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	// Posters come as JPEG, PNG or GIF.
+	_ "image/gif"
+)
+
+// ThumbnailWidths are the widths Thumbnail makes. Only these few are
+// allowed, so that requests cannot fill the disk with thumbnails of every
+// width.
+var ThumbnailWidths = []int{160, 320, 640}
+
+// ValidThumbnailWidth reports whether width is one of ThumbnailWidths.
+func ValidThumbnailWidth(width int) bool {
+	return slices.Contains(ThumbnailWidths, width)
+}
+
+var ErrNotFound = errors.New("poster not found")
+
+// Disk stores posters as files named by the SHA-256 of their content, so
+// the same image is only kept once however many items use it. Thumbnails
+// are made on demand and kept under thumbs/.
+type Disk struct {
+	dir string
+}
+
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Disk{dir: dir}, nil
+}
+
+// ValidHash reports whether s looks like a hash Put returns.
+func ValidHash(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func (d *Disk) path(hash string) string {
+	return filepath.Join(d.dir, hash[:2], hash)
+}
+
+// Put stores data unless an identical poster is already stored and returns
+// its hash.
+func (d *Disk) Put(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := d.path(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	return hash, writeFile(path, data)
+}
+
+// Path returns the file holding the poster with hash.
+func (d *Disk) Path(hash string) (string, error) {
+	if !ValidHash(hash) {
+		return "", ErrNotFound
+	}
+
+	path := d.path(hash)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// Thumbnail returns a file holding the poster with hash scaled down to
+// width, one of ThumbnailWidths, making it the first time it is asked for.
+// Posters no wider than width, in a format that cannot be decoded, or with
+// more than maxPixels, are returned as they are.
+func (d *Disk) Thumbnail(hash string, width int) (string, error) {
+	if !ValidThumbnailWidth(width) {
+		return "", fmt.Errorf("width must be one of %v", ThumbnailWidths)
+	}
+
+	orig, err := d.Path(hash)
+	if err != nil {
+		return "", err
+	}
+
+	thumb := filepath.Join(d.dir, "thumbs", hash[:2], fmt.Sprintf("%s-%d", hash, width))
+	if _, err := os.Stat(thumb); err == nil {
+		return thumb, nil
+	}
+
+	data, err := os.ReadFile(orig)
+	if err != nil {
+		return "", err
+	}
+	img, format, err := decode(data)
+	if err != nil || img.Bounds().Dx() <= width {
+		return orig, nil
+	}
+
+	var buf bytes.Buffer
+	scaled := scale(img, width)
+	if format == "png" || format == "gif" {
+		err = png.Encode(&buf, scaled)
+	} else {
+		err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return thumb, writeFile(thumb, buf.Bytes())
+}
+
+// writeFile writes data to a temporary file next to path and renames it, so
+// concurrent writers and readers never see a partial file.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,30 @@
+package posters
+
+import (
+	"fmt"
+	"image"
+)
+
+// perceptualHash returns the difference hash of img as 16 hex digits. img
+// is shrunk to 9x8 grey pixels and every bit says whether a pixel is
+// brighter than its right neighbour, so re-encoded, resized or slightly
+// recolored copies of a poster get hashes only a few bits apart.
+func perceptualHash(img image.Image) string {
+	small := resize(img, 9, 8)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luma(small, x, y) > luma(small, x+1, y) {
+				hash |= 1
+			}
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+func luma(img *image.RGBA64, x, y int) float64 {
+	c := img.RGBA64At(x, y)
+	return 0.299*float64(c.R) + 0.587*float64(c.G) + 0.114*float64(c.B)
+}
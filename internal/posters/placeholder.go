@@ -0,0 +1,172 @@
+package posters
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Blurhash components of poster placeholders: posters are portrait, so
+// they get more vertical than horizontal detail.
+const (
+	blurHashX = 3
+	blurHashY = 4
+)
+
+// placeholderWidth is the width posters are shrunk to before they are
+// analyzed; nothing analyze computes needs more detail.
+const placeholderWidth = 32
+
+// analyze returns the placeholders and perceptual hash of an encoded
+// image. They are all empty if it cannot be decoded or has more than
+// maxPixels.
+func analyze(data []byte) PosterInfo {
+	img, _, err := decode(data)
+	if err != nil || img.Bounds().Empty() {
+		return PosterInfo{}
+	}
+	if img.Bounds().Dx() > placeholderWidth {
+		img = scale(img, placeholderWidth)
+	}
+	return PosterInfo{
+		BlurHash: encodeBlurHash(img, blurHashX, blurHashY),
+		Color:    dominantColor(img),
+		PHash:    perceptualHash(img),
+	}
+}
+
+// dominantColor returns the most common color of img as "#rrggbb". Colors
+// are bucketed by their top four bits per channel, and the average of the
+// largest bucket is returned.
+func dominantColor(img image.Image) string {
+	type bucket struct {
+		n       int
+		r, g, b int
+	}
+	buckets := make(map[int]*bucket)
+	var best *bucket
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl := rgb8(img, x, y)
+			key := (r>>4)<<8 | (g>>4)<<4 | bl>>4
+
+			bk := buckets[key]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.n++
+			bk.r += r
+			bk.g += g
+			bk.b += bl
+
+			if best == nil || bk.n > best.n {
+				best = bk
+			}
+		}
+	}
+
+	return fmt.Sprintf("#%02x%02x%02x", best.r/best.n, best.g/best.n, best.b/best.n)
+}
+
+// rgb8 returns the 8-bit color of the pixel at x, y, composited over black.
+func rgb8(img image.Image, x, y int) (r, g, b int) {
+	cr, cg, cb, _ := img.At(x, y).RGBA()
+	return int(cr >> 8), int(cg >> 8), int(cb >> 8)
+}
+
+// encodeBlurHash implements the blurhash encoding described at
+// https://blurha.sh with xComp by yComp components.
+func encodeBlurHash(img image.Image, xComp, yComp int) string {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+
+	factors := make([][3]float64, 0, xComp*yComp)
+	for j := 0; j < yComp; j++ {
+		for i := 0; i < xComp; i++ {
+			norm := 2.0
+			if i == 0 && j == 0 {
+				norm = 1
+			}
+
+			var f [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := norm *
+						math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					r, g, bl := rgb8(img, b.Min.X+x, b.Min.Y+y)
+					f[0] += basis * srgbToLinear(r)
+					f[1] += basis * srgbToLinear(g)
+					f[2] += basis * srgbToLinear(bl)
+				}
+			}
+
+			scale := 1 / float64(width*height)
+			factors = append(factors, [3]float64{f[0] * scale, f[1] * scale, f[2] * scale})
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base83((xComp-1)+(yComp-1)*9, 1))
+
+	dc, ac := factors[0], factors[1:]
+
+	maxValue := 1.0
+	if len(ac) > 0 {
+		actualMax := 0.0
+		for _, f := range ac {
+			actualMax = max(actualMax, math.Abs(f[0]), math.Abs(f[1]), math.Abs(f[2]))
+		}
+		quantised := int(max(0, min(82, math.Floor(actualMax*166-0.5))))
+		maxValue = float64(quantised+1) / 166
+		sb.WriteString(base83(quantised, 1))
+	} else {
+		sb.WriteString(base83(0, 1))
+	}
+
+	sb.WriteString(base83(linearToSRGB(dc[0])<<16|linearToSRGB(dc[1])<<8|linearToSRGB(dc[2]), 4))
+
+	for _, f := range ac {
+		q := func(v float64) int {
+			return int(max(0, min(18, math.Floor(signPow(v/maxValue, 0.5)*9+9.5))))
+		}
+		sb.WriteString(base83(q(f[0])*19*19+q(f[1])*19+q(f[2]), 2))
+	}
+
+	return sb.String()
+}
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+func base83(value, length int) string {
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = base83Chars[value%83]
+		value /= 83
+	}
+	return string(out)
+}
+
+func srgbToLinear(v int) float64 {
+	f := float64(v) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(v float64) int {
+	v = max(0, min(1, v))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(v, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(v), exp), v)
+}
@@ -0,0 +1,156 @@
+// Package posters mirrors the poster images of stored items to local disk
+// so they can be served by content hash instead of hotlinked.
+package posters
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+)
+
+type (
+	PosterRef  = models.PosterRef
+	PosterInfo = models.PosterInfo
+)
+
+// userAgent is sent with every download, like the scraper's requests.
+const userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0"
+
+// maxPosterSize is the largest image Mirror downloads.
+const maxPosterSize = 10 << 20
+
+type Options struct {
+	// Concurrency is how many posters are downloaded at once.
+	Concurrency int
+	// Store is where items are read from and hashes written to. Nil means
+	// MongoDB through the models package.
+	Store Store
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Concurrency: 4,
+	}
+}
+
+// Summary counts the outcomes of one Mirror call.
+type Summary struct {
+	Mirrored int
+	Failed   int
+}
+
+// URL is where the API serves the poster with hash.
+func URL(hash string) string {
+	return "/api/images/" + hash
+}
+
+// Mirror downloads the poster of every item in the given collections that
+// was not mirrored yet, or changed since, into disk and records its hash,
+// placeholders and perceptual hash on the item. A poster that fails is
+// logged and tried again next time.
+func Mirror(client *http.Client, disk *Disk, collections []string, opts Options) (Summary, error) {
+	store := opts.store()
+	var summary Summary
+
+	for _, collection := range collections {
+		refs, err := store.Unmirrored(collection)
+		if err != nil {
+			return summary, fmt.Errorf("failed to load %s posters: %w", collection, err)
+		}
+		if len(refs) == 0 {
+			continue
+		}
+
+		log.Printf("Mirroring %d %s poster(s)", len(refs), collection)
+
+		var (
+			mu   sync.Mutex
+			wg   sync.WaitGroup
+			sema = make(chan struct{}, max(opts.Concurrency, 1))
+		)
+		for _, ref := range refs {
+			sema <- struct{}{}
+			wg.Add(1)
+
+			go func(ref PosterRef) {
+				defer func() {
+					<-sema
+					wg.Done()
+				}()
+
+				err := mirrorOne(client, disk, store, collection, ref)
+				if err != nil {
+					log.Printf("Failed to mirror poster %s: %v", ref.Image, err)
+				}
+
+				mu.Lock()
+				if err != nil {
+					summary.Failed++
+				} else {
+					summary.Mirrored++
+				}
+				mu.Unlock()
+			}(ref)
+		}
+		wg.Wait()
+	}
+
+	return summary, nil
+}
+
+func mirrorOne(client *http.Client, disk *Disk, store Store, collection string, ref PosterRef) error {
+	data, err := download(client, ref.Image, ref.Link)
+	if err != nil {
+		return err
+	}
+
+	hash, err := disk.Put(data)
+	if err != nil {
+		return err
+	}
+
+	info := analyze(data)
+	info.Hash = hash
+	return store.SetPoster(collection, ref.Link, ref.Image, info)
+}
+
+// download fetches an image, sending the page it appears on as referer.
+func download(client *http.Client, imageURL, referer string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "image/*")
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPosterSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPosterSize {
+		return nil, fmt.Errorf("larger than %d bytes", maxPosterSize)
+	}
+	if ct := http.DetectContentType(data); !strings.HasPrefix(ct, "image/") {
+		return nil, fmt.Errorf("not an image but %s", ct)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,246 @@
+package posters
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math/bits"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// memStore is a Store backed by maps, keyed by collection.
+type memStore struct {
+	mu     sync.Mutex
+	refs   map[string][]PosterRef
+	hashes map[string]string
+	infos  map[string]PosterInfo
+}
+
+func (m *memStore) Unmirrored(collection string) ([]PosterRef, error) {
+	return m.refs[collection], nil
+}
+
+func (m *memStore) SetPoster(collection, link, source string, info PosterInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.hashes == nil {
+		m.hashes = make(map[string]string)
+		m.infos = make(map[string]PosterInfo)
+	}
+	m.hashes[link] = info.Hash
+	m.infos[link] = info
+	return nil
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMirrorStoresPostersByContent(t *testing.T) {
+	poster := testPNG(t, 200, 300)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a.png", "/same-as-a.png":
+			w.Write(poster)
+		case "/page.html":
+			w.Write([]byte("<html><body>not an image</body></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	disk, err := NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDisk: %v", err)
+	}
+	store := &memStore{refs: map[string][]PosterRef{
+		"movies": {
+			{Link: "/movie-a", Image: srv.URL + "/a.png"},
+			{Link: "/movie-b", Image: srv.URL + "/same-as-a.png"},
+			{Link: "/movie-c", Image: srv.URL + "/page.html"},
+			{Link: "/movie-d", Image: srv.URL + "/missing.png"},
+		},
+	}}
+
+	summary, err := Mirror(srv.Client(), disk, []string{"movies"}, Options{Concurrency: 2, Store: store})
+	if err != nil {
+		t.Fatalf("Mirror: %v", err)
+	}
+	if summary.Mirrored != 2 || summary.Failed != 2 {
+		t.Errorf("summary = %+v, want 2 mirrored and 2 failed", summary)
+	}
+
+	hash := store.hashes["/movie-a"]
+	if !ValidHash(hash) || store.hashes["/movie-b"] != hash {
+		t.Fatalf("hashes = %v, want the same hash for both copies", store.hashes)
+	}
+	if _, ok := store.hashes["/movie-c"]; ok {
+		t.Errorf("recorded a hash for a page that is not an image")
+	}
+
+	if info := store.infos["/movie-a"]; len(info.BlurHash) != 2+4+2*(blurHashX*blurHashY-1) || info.Color == "" {
+		t.Errorf("placeholders = %q, %q", info.BlurHash, info.Color)
+	}
+
+	path, err := disk.Path(hash)
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, poster) {
+		t.Errorf("stored poster differs from the downloaded one")
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	disk, err := NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDisk: %v", err)
+	}
+	hash, err := disk.Put(testPNG(t, 400, 600))
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	thumb, err := disk.Thumbnail(hash, 160)
+	if err != nil {
+		t.Fatalf("Thumbnail: %v", err)
+	}
+	f, err := os.Open(thumb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(f)
+	f.Close()
+	if err != nil || cfg.Width != 160 || cfg.Height != 240 {
+		t.Errorf("thumbnail is %dx%d (%v), want 160x240", cfg.Width, cfg.Height, err)
+	}
+
+	again, err := disk.Thumbnail(hash, 160)
+	if err != nil || again != thumb {
+		t.Errorf("second Thumbnail = %q, %v, want the cached %q", again, err, thumb)
+	}
+
+	orig, _ := disk.Path(hash)
+	if wide, err := disk.Thumbnail(hash, 640); err != nil || wide != orig {
+		t.Errorf("Thumbnail wider than the poster = %q, %v, want the original", wide, err)
+	}
+
+	if _, err := disk.Thumbnail(hash, 161); err == nil {
+		t.Errorf("Thumbnail of a width not in ThumbnailWidths succeeded")
+	}
+
+	if _, err := disk.Path("not-a-hash"); err != ErrNotFound {
+		t.Errorf("Path of an invalid hash = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPlaceholdersOfSolidImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 60, 90))
+	for y := range 90 {
+		for x := range 60 {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	// A stripe that must not win over the red bulk.
+	for y := range 10 {
+		for x := range 60 {
+			img.Set(x, y, color.RGBA{B: 255, A: 255})
+		}
+	}
+
+	if got := dominantColor(img); got != "#ff0000" {
+		t.Errorf("dominantColor = %s, want #ff0000", got)
+	}
+
+	solid := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for y := range 8 {
+		for x := range 8 {
+			solid.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	// The hash starts with the size flag and, after the AC maximum, the
+	// average color.
+	got := encodeBlurHash(solid, blurHashX, blurHashY)
+	if len(got) != 28 || got[:1] != base83(2+3*9, 1) || got[2:6] != base83(0xff0000, 4) {
+		t.Errorf("encodeBlurHash = %s, want a 28 character hash of red", got)
+	}
+}
+
+func TestPerceptualHashSurvivesResizing(t *testing.T) {
+	big, _, err := image.Decode(bytes.NewReader(testPNG(t, 200, 300)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	small := scale(big, 70)
+
+	a, err := strconv.ParseUint(perceptualHash(big), 16, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := strconv.ParseUint(perceptualHash(small), 16, 64)
+	if d := bits.OnesCount64(a ^ b); d > 4 {
+		t.Errorf("hashes of the same poster at two sizes differ in %d bits", d)
+	}
+
+	flipped := image.NewRGBA(big.Bounds())
+	for y := range 300 {
+		for x := range 200 {
+			flipped.Set(199-x, y, big.At(x, y))
+		}
+	}
+	c, _ := strconv.ParseUint(perceptualHash(flipped), 16, 64)
+	if d := bits.OnesCount64(a ^ c); d < 16 {
+		t.Errorf("hashes of different posters differ in only %d bits", d)
+	}
+}
+
+func TestDecodeRefusesHugeImages(t *testing.T) {
+	// A valid 1x1 PNG whose header claims 30000x30000 pixels.
+	data := testPNG(t, 1, 1)
+	binary.BigEndian.PutUint32(data[16:], 30000)
+	binary.BigEndian.PutUint32(data[20:], 30000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	if _, _, err := decode(data); err != errTooLarge {
+		t.Fatalf("decode = %v, want errTooLarge", err)
+	}
+	if info := analyze(data); info != (PosterInfo{}) {
+		t.Errorf("analyze = %+v, want nothing", info)
+	}
+
+	disk, err := NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDisk: %v", err)
+	}
+	hash, err := disk.Put(data)
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	orig, _ := disk.Path(hash)
+	if thumb, err := disk.Thumbnail(hash, 160); err != nil || thumb != orig {
+		t.Errorf("Thumbnail = %q, %v, want the original", thumb, err)
+	}
+}
@@ -0,0 +1,72 @@
+package posters
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+)
+
+// maxPixels is the most pixels an image may have to be decoded. An image
+// file declares its size up front, so a small download can otherwise ask
+// for gigabytes once decoded.
+const maxPixels = 25_000_000
+
+var errTooLarge = errors.New("image too large to decode")
+
+// decode decodes data after checking that its declared size is within
+// maxPixels.
+func decode(data []byte) (image.Image, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxPixels/cfg.Height {
+		return nil, "", errTooLarge
+	}
+	return image.Decode(bytes.NewReader(data))
+}
+
+// scale shrinks img to width, keeping its aspect ratio.
+func scale(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	return resize(img, width, max(1, b.Dy()*width/b.Dx()))
+}
+
+// resize shrinks img to width by height by averaging the block of source
+// pixels behind every destination pixel.
+func resize(img image.Image, width, height int) *image.RGBA64 {
+	b := img.Bounds()
+	dst := image.NewRGBA64(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(b.Min.X+(x+1)*b.Dx()/width, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,27 @@
+package posters
+
+import "github.com/Ka10ken1/mykadri-scraper/internal/models"
+
+// Store is everything the poster mirror reads from and writes to persistent
+// storage.
+type Store interface {
+	Unmirrored(collection string) ([]PosterRef, error)
+	SetPoster(collection, link, source string, info PosterInfo) error
+}
+
+type mongoStore struct{}
+
+func (mongoStore) Unmirrored(collection string) ([]PosterRef, error) {
+	return models.GetUnmirroredPosters(collection)
+}
+
+func (mongoStore) SetPoster(collection, link, source string, info PosterInfo) error {
+	return models.SetPoster(collection, link, source, info)
+}
+
+func (o Options) store() Store {
+	if o.Store != nil {
+		return o.Store
+	}
+	return mongoStore{}
+}
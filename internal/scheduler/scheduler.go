@@ -0,0 +1,151 @@
+// Package scheduler runs jobs such as periodic scrapes on cron-style
+// schedules inside the API server process.
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+type Job struct {
+	Name string
+	// Schedule is a standard five-field cron expression or a descriptor
+	// such as "@hourly" or "@every 6h".
+	Schedule string
+	// Jitter is the most a run is started after its scheduled time, picked
+	// at random for every run so jobs on the same schedule do not start at
+	// the same moment.
+	Jitter time.Duration
+	Run    func() error
+}
+
+// Status is what the scheduler knows about one job.
+type Status struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Running  bool      `json:"running"`
+	NextRun  time.Time `json:"nextRun,omitzero"`
+	// LastStarted and LastFinished describe the latest run, LastError how
+	// it failed if it did.
+	LastStarted  time.Time `json:"lastStarted,omitzero"`
+	LastFinished time.Time `json:"lastFinished,omitzero"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+// Scheduler runs its jobs one at a time: a job that comes due while another
+// is running waits for it, and a job that is still running when its next
+// time comes skips that run.
+type Scheduler struct {
+	// runMu is held for the duration of every run.
+	runMu sync.Mutex
+
+	mu   sync.Mutex
+	jobs []*job
+}
+
+type job struct {
+	Job
+	schedule cron.Schedule
+	status   Status
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers a job. It must be called before Start.
+func (s *Scheduler) Add(j Job) error {
+	schedule, err := cron.ParseStandard(j.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q for %s: %w", j.Schedule, j.Name, err)
+	}
+	s.add(j, schedule)
+	return nil
+}
+
+func (s *Scheduler) add(j Job, schedule cron.Schedule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs = append(s.jobs, &job{
+		Job:      j,
+		schedule: schedule,
+		status:   Status{Name: j.Name, Schedule: j.Schedule},
+	})
+}
+
+// Start runs every job on its schedule in the background, forever.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, j := range s.jobs {
+		go s.loop(j)
+	}
+}
+
+// Statuses returns the status of every job, by name.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, j.status)
+	}
+	sort.Slice(statuses, func(a, b int) bool { return statuses[a].Name < statuses[b].Name })
+	return statuses
+}
+
+func (s *Scheduler) loop(j *job) {
+	for {
+		next := j.schedule.Next(time.Now())
+		if j.Jitter > 0 {
+			next = next.Add(rand.N(j.Jitter))
+		}
+
+		s.mu.Lock()
+		j.status.NextRun = next
+		s.mu.Unlock()
+
+		log.Printf("Next %s run at %s", j.Name, next.Format(time.RFC3339))
+		time.Sleep(time.Until(next))
+
+		s.run(j)
+	}
+}
+
+func (s *Scheduler) run(j *job) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.mu.Lock()
+	j.status.Running = true
+	j.status.NextRun = time.Time{}
+	j.status.LastStarted = time.Now()
+	s.mu.Unlock()
+
+	log.Printf("Starting scheduled %s run", j.Name)
+	err := j.Run()
+
+	s.mu.Lock()
+	j.status.Running = false
+	j.status.LastFinished = time.Now()
+	j.status.LastError = ""
+	if err != nil {
+		j.status.LastError = err.Error()
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("Scheduled %s run failed: %v", j.Name, err)
+	} else {
+		log.Printf("Scheduled %s run finished", j.Name)
+	}
+}
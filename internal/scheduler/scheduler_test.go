@@ -0,0 +1,64 @@
+package scheduler
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// every is a cron.Schedule firing at a fixed interval, shorter than the one
+// second cron descriptors allow.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+func TestAddRejectsBadSchedule(t *testing.T) {
+	s := New()
+	if err := s.Add(Job{Name: "movies", Schedule: "every now and then"}); err == nil {
+		t.Errorf("Add accepted an invalid schedule")
+	}
+	if err := s.Add(Job{Name: "movies", Schedule: "0 */6 * * *"}); err != nil {
+		t.Errorf("Add: %v", err)
+	}
+}
+
+func TestJobsNeverOverlap(t *testing.T) {
+	s := New()
+
+	var running, overlaps, runs atomic.Int32
+	work := func() error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		defer running.Add(-1)
+
+		runs.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		return errors.New("site down")
+	}
+
+	s.add(Job{Name: "movies", Schedule: "test", Jitter: 5 * time.Millisecond, Run: work}, every(10*time.Millisecond))
+	s.add(Job{Name: "shows", Schedule: "test", Run: work}, every(10*time.Millisecond))
+	s.Start()
+
+	time.Sleep(300 * time.Millisecond)
+
+	if overlaps.Load() != 0 {
+		t.Errorf("%d runs overlapped another", overlaps.Load())
+	}
+	if runs.Load() < 2 {
+		t.Errorf("got %d runs, want at least one per job", runs.Load())
+	}
+
+	for _, st := range s.Statuses() {
+		if st.LastStarted.IsZero() || st.LastError != "site down" {
+			t.Errorf("%s status = %+v, want a failed run recorded", st.Name, st)
+		}
+		if st.NextRun.IsZero() && !st.Running {
+			t.Errorf("%s has neither a next run nor a running one", st.Name)
+		}
+	}
+}
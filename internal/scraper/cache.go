@@ -0,0 +1,133 @@
+package scraper
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// CacheOptions turns on colly's on-disk response cache, meant for
+// development re-runs. Cached responses skip the politeness delay, so a
+// re-run only goes to the site for entries that expired.
+type CacheOptions struct {
+	// Dir is where responses are stored. Empty disables the cache.
+	Dir string
+	// ListingTTL and DetailTTL are how long cached listing and detail
+	// pages are reused.
+	ListingTTL time.Duration
+	DetailTTL  time.Duration
+}
+
+// cacheFile is where colly stores the response for url under dir.
+func cacheFile(dir, url string) string {
+	sum := sha1.Sum([]byte(url))
+	hash := hex.EncodeToString(sum[:])
+	return filepath.Join(dir, hash[:2], hash)
+}
+
+// useCache makes c read and write responses in the cache. Entries older
+// than their TTL are dropped before the request so colly fetches them again,
+// and failed responses are dropped so that retries reach the site. It must
+// be called before handleErrors so the entry is gone when a retry starts.
+func useCache(c *colly.Collector, opts CacheOptions) {
+	if opts.Dir == "" {
+		return
+	}
+	c.CacheDir = opts.Dir
+
+	c.OnRequest(func(r *colly.Request) {
+		ttl := opts.DetailTTL
+		if _, listing := r.Ctx.GetAny(pageKey).(int); listing {
+			ttl = opts.ListingTTL
+		}
+
+		file := cacheFile(opts.Dir, r.URL.String())
+		info, err := os.Stat(file)
+		if err == nil && time.Since(info.ModTime()) > ttl {
+			removeCacheFile(file)
+		}
+	})
+
+	c.OnError(func(r *colly.Response, _ error) {
+		if r != nil && r.Request != nil {
+			removeCacheFile(cacheFile(opts.Dir, r.Request.URL.String()))
+		}
+	})
+}
+
+func removeCacheFile(file string) {
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("Failed to drop cached response %s: %v", file, err)
+	}
+}
+
+// CacheStats describes the contents of a response cache directory.
+type CacheStats struct {
+	Entries int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+}
+
+// ReadCacheStats walks the cache in dir. A missing directory is an empty
+// cache.
+func ReadCacheStats(dir string) (CacheStats, error) {
+	var stats CacheStats
+	err := walkCache(dir, func(_ string, info fs.FileInfo) error {
+		stats.Entries++
+		stats.Bytes += info.Size()
+		if stats.Oldest.IsZero() || info.ModTime().Before(stats.Oldest) {
+			stats.Oldest = info.ModTime()
+		}
+		if info.ModTime().After(stats.Newest) {
+			stats.Newest = info.ModTime()
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// PurgeCache removes the cached responses in dir that are older than
+// olderThan, or all of them when olderThan is zero, and returns how many it
+// removed.
+func PurgeCache(dir string, olderThan time.Duration) (int, error) {
+	removed := 0
+	err := walkCache(dir, func(path string, info fs.FileInfo) error {
+		if olderThan > 0 && time.Since(info.ModTime()) <= olderThan {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+func walkCache(dir string, fn func(path string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(path, info)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+package scraper
+
+import "regexp"
+
+type Kind string
+
+const (
+	KindMovie Kind = "movie"
+	KindShow  Kind = "show"
+)
+
+// Category describes one mykadri.tv section. Adding a section such as
+// cartoons or documentaries only takes a new entry in Categories.
+type Category struct {
+	Name string
+	// ListingPath is the listing page path template; %d is the page number.
+	ListingPath string
+	Kind        Kind
+	// EmbedPattern matches the category's usual player embed on a detail
+	// page. Its first submatch is preferred as the item's VideoURL; other
+	// players are still kept in Sources.
+	EmbedPattern *regexp.Regexp
+	// Collection is the Mongo collection the category's items are stored in.
+	Collection string
+	// Parallelism is how many listing pages a full crawl fetches at once.
+	Parallelism int
+}
+
+var MovieCategory = &Category{
+	Name:         "movies",
+	ListingPath:  "/filmebi_qartulad/page/%d/",
+	Kind:         KindMovie,
+	EmbedPattern: regexp.MustCompile(`data-lazy="(https://vidsrc\.me/embed/movie\?imdb=tt\d+)"`),
+	Collection:   "movies",
+	Parallelism:  2,
+}
+
+var ShowCategory = &Category{
+	Name:         "shows",
+	ListingPath:  "/serialebi_qartulad/page/%d/",
+	Kind:         KindShow,
+	EmbedPattern: regexp.MustCompile(`data-lazy="(https://vidsrc\.me/embed/tv\?imdb=tt\d+)"`),
+	Collection:   "shows",
+	Parallelism:  1,
+}
+
+var Categories = []*Category{
+	MovieCategory,
+	ShowCategory,
+}
+
+func CategoryByName(name string) (*Category, bool) {
+	for _, c := range Categories {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+)
+
+type Change = models.Change
+
+// trackedField is a scraped Item field that re-scrapes compare against the
+// stored document. Listing fields are known from the listing page alone;
+// the rest need the detail page.
+type trackedField struct {
+	name    string
+	listing bool
+	get     func(*Item) any
+}
+
+var trackedFields = []trackedField{
+	{"title", true, func(it *Item) any { return it.Title }},
+	{"titleEnglish", true, func(it *Item) any { return it.TitleEnglish }},
+	{"year", true, func(it *Item) any { return it.Year }},
+	{"image", true, func(it *Item) any { return it.Image }},
+	{"videoUrl", false, func(it *Item) any { return it.VideoURL }},
+	{"imdbId", false, func(it *Item) any { return it.ImdbID }},
+	{"sources", false, func(it *Item) any { return it.Sources }},
+	{"description", false, func(it *Item) any { return it.Description }},
+	{"genres", false, func(it *Item) any { return it.Genres }},
+	{"rating", false, func(it *Item) any { return it.Rating }},
+	{"runtime", false, func(it *Item) any { return it.Runtime }},
+	{"country", false, func(it *Item) any { return it.Country }},
+	{"director", false, func(it *Item) any { return it.Director }},
+	{"actors", false, func(it *Item) any { return it.Actors }},
+	{"seasons", false, func(it *Item) any { return it.Seasons }},
+}
+
+// diffItem returns the tracked fields whose value in scraped differs from
+// stored. Only listing fields are compared unless withDetail is set. A field
+// the scrape came back empty for is never reported, so a page that fails to
+// show something does not wipe what is stored.
+func diffItem(stored, scraped *Item, withDetail bool) []Change {
+	var changes []Change
+	for _, f := range trackedFields {
+		if !f.listing && !withDetail {
+			continue
+		}
+
+		old, cur := f.get(stored), f.get(scraped)
+		if isEmpty(cur) || reflect.DeepEqual(old, cur) {
+			continue
+		}
+
+		changes = append(changes, Change{Field: f.name, Old: old, New: cur})
+	}
+	return changes
+}
+
+func isEmpty(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+// updateStored compares a re-scraped item with its stored document and
+// writes back whatever changed.
+func (cr *crawl) updateStored(item Item, withDetail bool) {
+	stored, err := cr.store.Item(cr.cat.Collection, item.Link)
+	if err != nil {
+		log.Printf("Warning: could not load stored %s: %v", item.Link, err)
+		return
+	}
+	if stored == nil {
+		return
+	}
+
+	changes := diffItem(stored, &item, withDetail)
+	if stored.Delisted {
+		changes = append(changes, Change{Field: "delisted", Old: true, New: false})
+	}
+	if len(changes) == 0 {
+		cr.stats.addSkipped()
+		return
+	}
+
+	now := time.Now()
+	for i := range changes {
+		changes[i].Collection = cr.cat.Collection
+		changes[i].Link = item.Link
+		changes[i].ChangedAt = now
+	}
+
+	if err := cr.store.Update(cr.cat.Collection, item.Link, changes); err != nil {
+		log.Printf("Warning: could not update %s: %v", item.Link, err)
+		cr.failures.add(Failure{URL: item.Link, Attempts: 1, Reason: "update failed: " + err.Error()})
+		return
+	}
+
+	for _, ch := range changes {
+		log.Printf("Updated %s of %s: %v -> %v", ch.Field, item.Link, ch.Old, ch.New)
+	}
+
+	cr.mu.Lock()
+	cr.updated++
+	cr.mu.Unlock()
+}
@@ -0,0 +1,236 @@
+package scraper
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+)
+
+type Checkpoint = models.CrawlState
+
+// checkpointFlushInterval is how often a changed checkpoint is written to
+// the store. Writing it on every change would rewrite the whole state about
+// twice per title.
+const checkpointFlushInterval = 5 * time.Second
+
+// checkpointer keeps the crawl state of one category in memory and writes
+// it to the store every checkpointFlushInterval while it has changed. A
+// page is only completed after its entries were added as pending, so every
+// write is a consistent state to resume from.
+type checkpointer struct {
+	mu sync.Mutex
+	// store is nil for a checkpoint that is only kept in memory.
+	store Store
+	state *Checkpoint
+	// resumed is set when the crawl picked up an earlier run's state.
+	resumed bool
+	// dirty is set when state has changed since it was last written.
+	dirty bool
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// startCheckpoint resumes the stored checkpoint of cat when it was written
+// by a crawl in the same mode, and starts a fresh one otherwise.
+func startCheckpoint(store Store, cat *Category, mode Mode) (*checkpointer, error) {
+	state, err := store.LoadCheckpoint(cat.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	resumed := false
+	switch {
+	case state == nil:
+	case state.Mode != mode.String():
+		log.Printf("Discarding %s checkpoint from a %s crawl", cat.Name, state.Mode)
+		state = nil
+	default:
+		log.Printf("Resuming %s crawl started at %s: %d page(s) done, %d detail page(s) pending",
+			cat.Name, state.StartedAt.Format(time.RFC3339), len(state.CompletedPages), len(state.PendingDetails))
+		resumed = true
+	}
+
+	if state == nil {
+		state = &Checkpoint{
+			Category:  cat.Name,
+			Mode:      mode.String(),
+			StartedAt: time.Now(),
+		}
+	}
+
+	cp := &checkpointer{
+		store:   store,
+		state:   state,
+		resumed: resumed,
+		dirty:   true,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	cp.flush()
+	go cp.flushEvery(checkpointFlushInterval)
+
+	return cp, nil
+}
+
+// memoryCheckpoint starts a checkpoint that is never loaded from or
+// written to the store. A crawl of a range of pages uses one, so it neither
+// resumes nor clears the checkpoint of a crawl of every page.
+func memoryCheckpoint(cat *Category, mode Mode) *checkpointer {
+	cp := &checkpointer{
+		state: &Checkpoint{
+			Category:  cat.Name,
+			Mode:      mode.String(),
+			StartedAt: time.Now(),
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	close(cp.done)
+	return cp
+}
+
+func (cp *checkpointer) flushEvery(interval time.Duration) {
+	defer close(cp.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cp.flush()
+		case <-cp.stop:
+			return
+		}
+	}
+}
+
+func (cp *checkpointer) setLastPage(n int) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.state.LastPage = n
+	cp.dirty = true
+}
+
+func (cp *checkpointer) pending() []Item {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return slices.Clone(cp.state.PendingDetails)
+}
+
+func (cp *checkpointer) pageDone(page int) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return slices.Contains(cp.state.CompletedPages, page)
+}
+
+func (cp *checkpointer) completePage(page int) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if slices.Contains(cp.state.CompletedPages, page) {
+		return
+	}
+	cp.state.CompletedPages = append(cp.state.CompletedPages, page)
+	cp.dirty = true
+}
+
+// complete reports whether this run, on its own, visited every listing page
+// up to the last one.
+func (cp *checkpointer) complete() bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if cp.resumed || cp.state.LastPage == 0 {
+		return false
+	}
+	for page := 1; page <= cp.state.LastPage; page++ {
+		if !slices.Contains(cp.state.CompletedPages, page) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cp *checkpointer) addPending(item Item) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if slices.ContainsFunc(cp.state.PendingDetails, func(it Item) bool { return it.Link == item.Link }) {
+		return
+	}
+	cp.state.PendingDetails = append(cp.state.PendingDetails, item)
+	cp.dirty = true
+}
+
+func (cp *checkpointer) resolvePending(link string) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	before := len(cp.state.PendingDetails)
+	cp.state.PendingDetails = slices.DeleteFunc(cp.state.PendingDetails, func(it Item) bool { return it.Link == link })
+	if len(cp.state.PendingDetails) != before {
+		cp.dirty = true
+	}
+}
+
+// finish removes the checkpoint once the crawl has run to the end.
+func (cp *checkpointer) finish() {
+	cp.stopFlushing()
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.dirty = false
+	if cp.store == nil {
+		return
+	}
+	if err := cp.store.ClearCheckpoint(cp.state.Category); err != nil {
+		log.Printf("Failed to clear %s checkpoint: %v", cp.state.Category, err)
+	}
+}
+
+// close writes what is left of the checkpoint of a crawl that stops before
+// its end. It does nothing after finish.
+func (cp *checkpointer) close() {
+	cp.stopFlushing()
+	cp.flush()
+}
+
+func (cp *checkpointer) stopFlushing() {
+	cp.closeOnce.Do(func() {
+		close(cp.stop)
+		<-cp.done
+	})
+}
+
+// flush writes the state if it has changed. The write happens outside
+// cp.mu on a copy, so the crawl is not held up by it. A failed write is
+// logged rather than aborting the crawl and is tried again on the next
+// flush.
+func (cp *checkpointer) flush() {
+	cp.mu.Lock()
+	if !cp.dirty || cp.store == nil {
+		cp.mu.Unlock()
+		return
+	}
+	state := *cp.state
+	state.CompletedPages = slices.Clone(state.CompletedPages)
+	state.PendingDetails = slices.Clone(state.PendingDetails)
+	cp.dirty = false
+	cp.mu.Unlock()
+
+	if err := cp.store.SaveCheckpoint(&state); err != nil {
+		log.Printf("Failed to save %s checkpoint: %v", state.Category, err)
+		cp.mu.Lock()
+		cp.dirty = true
+		cp.mu.Unlock()
+	}
+}
@@ -0,0 +1,120 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// pageTally records, per listing page URL, how many posts the page had and
+// how many of them were links we had not seen before.
+type pageTally struct {
+	mu    sync.Mutex
+	posts map[string]int
+	fresh map[string]int
+}
+
+func newPageTally() *pageTally {
+	return &pageTally{
+		posts: make(map[string]int),
+		fresh: make(map[string]int),
+	}
+}
+
+func (t *pageTally) add(pageURL string, isNew bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.posts[pageURL]++
+	if isNew {
+		t.fresh[pageURL]++
+	}
+}
+
+func (t *pageTally) get(pageURL string) (posts, fresh int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.posts[pageURL], t.fresh[pageURL]
+}
+
+const pageKey = "page"
+
+// visitPage queues a listing page, tagging the request with its page number.
+func visitPage(c *colly.Collector, urlTemplate string, page int) {
+	url := fmt.Sprintf(urlTemplate, page)
+
+	ctx := colly.NewContext()
+	ctx.Put(pageKey, page)
+
+	if err := c.Request(http.MethodGet, url, nil, ctx, nil); err != nil {
+		log.Println("Failed to visit", url, err)
+	}
+}
+
+// crawlListing visits the listing pages of urlTemplate from opts.FirstPage
+// on, skipping those done reports as already completed by an earlier,
+// interrupted run. In Full mode every page up to maxPages is visited with at
+// most parallel pages in flight. In Incremental mode pages are visited one at a time, newest first,
+// and the walk stops after opts.StopAfter consecutive pages without a new
+// link.
+func crawlListing(c *colly.Collector, urlTemplate string, maxPages, parallel int, opts Options, tally *pageTally, done func(page int) bool) {
+	if opts.Mode == Full {
+		var wg sync.WaitGroup
+		sema := make(chan struct{}, parallel)
+
+		for i := max(opts.FirstPage, 1); i <= maxPages; i++ {
+			if done(i) {
+				continue
+			}
+
+			sema <- struct{}{}
+			wg.Add(1)
+
+			go func(page int) {
+				defer func() {
+					<-sema
+					wg.Done()
+				}()
+				visitPage(c, urlTemplate, page)
+			}(i)
+		}
+
+		wg.Wait()
+		c.Wait()
+		return
+	}
+
+	stopAfter := max(opts.StopAfter, 1)
+	staleRun := 0
+
+	for page := max(opts.FirstPage, 1); page <= maxPages; page++ {
+		if done(page) {
+			continue
+		}
+
+		url := fmt.Sprintf(urlTemplate, page)
+		visitPage(c, urlTemplate, page)
+		c.Wait()
+
+		posts, fresh := tally.get(url)
+		if posts == 0 {
+			log.Printf("No posts on %s, stopping incremental crawl", url)
+			return
+		}
+
+		if fresh > 0 {
+			staleRun = 0
+			continue
+		}
+
+		staleRun++
+		if staleRun >= stopAfter {
+			log.Printf("%d consecutive pages without new links, stopping incremental crawl at page %d", staleRun, page)
+			return
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package scraper
+
+import (
+	"log"
+	"time"
+)
+
+// delistMissing marks the stored items of the category that this crawl did
+// not come across on any listing page as delisted. It only trusts a crawl
+// that visited every listing page itself: a resumed crawl or one that gave
+// up on a page would delist titles it simply did not get to.
+func (cr *crawl) delistMissing() {
+	if !cr.checkpoint.complete() {
+		log.Printf("Not checking %s for delisted titles: the crawl did not visit every listing page in this run", cr.cat.Name)
+		return
+	}
+
+	cr.mu.Lock()
+	listed := make([]string, 0, len(cr.listed))
+	for link := range cr.listed {
+		listed = append(listed, link)
+	}
+	cr.mu.Unlock()
+
+	if len(listed) == 0 {
+		return
+	}
+
+	n, err := cr.store.Delist(cr.cat.Collection, listed, time.Now())
+	if err != nil {
+		log.Printf("Warning: could not mark delisted %s: %v", cr.cat.Name, err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Marked %d %s as delisted", n, cr.cat.Name)
+	}
+}
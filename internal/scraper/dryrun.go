@@ -0,0 +1,88 @@
+package scraper
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// DryRunStore is a Store that writes scraped items to a stream as JSON
+// lines instead of saving them. It knows no stored items, keeps no
+// checkpoints and never touches MongoDB, so a crawl through it treats every
+// title as new.
+type DryRunStore struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	runs []ScrapeRun
+}
+
+func NewDryRunStore(w io.Writer) *DryRunStore {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &DryRunStore{enc: enc}
+}
+
+// Runs returns the finished runs that went through the store.
+func (s *DryRunStore) Runs() []ScrapeRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]ScrapeRun(nil), s.runs...)
+}
+
+func (s *DryRunStore) Links(collection string) ([]string, error) {
+	return nil, nil
+}
+
+func (s *DryRunStore) ImdbIDs(collection string) ([]string, error) {
+	return nil, nil
+}
+
+func (s *DryRunStore) Save(collection string, items []Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, it := range items {
+		if err := s.enc.Encode(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *DryRunStore) Item(collection, link string) (*Item, error) {
+	return nil, nil
+}
+
+func (s *DryRunStore) Update(collection, link string, changes []Change) error {
+	return nil
+}
+
+func (s *DryRunStore) Delist(collection string, listed []string, at time.Time) (int, error) {
+	return 0, nil
+}
+
+func (s *DryRunStore) SaveRun(run *ScrapeRun) error {
+	if run.FinishedAt.IsZero() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs = append(s.runs, *run)
+	return nil
+}
+
+func (s *DryRunStore) LoadCheckpoint(category string) (*Checkpoint, error) {
+	return nil, nil
+}
+
+func (s *DryRunStore) SaveCheckpoint(cp *Checkpoint) error {
+	return nil
+}
+
+func (s *DryRunStore) ClearCheckpoint(category string) error {
+	return nil
+}
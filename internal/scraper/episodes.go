@@ -0,0 +1,150 @@
+package scraper
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/PuerkitoBio/goquery"
+)
+
+type (
+	Season  = models.Season
+	Episode = models.Episode
+)
+
+const (
+	seasonSelector  = "div.seasons div.season, ul.seasons > li.season"
+	episodeSelector = ".episode, li[data-episode]"
+)
+
+var (
+	seasonNumberPattern  = regexp.MustCompile(`(?i)(?:season|სეზონი)\s*(\d+)|(\d+)\s*(?:season|სეზონი)`)
+	episodeNumberPattern = regexp.MustCompile(`(?i)(?:episode|სერია|ეპიზოდი)\s*(\d+)|(\d+)\s*(?:episode|სერია|ეპიზოდი)`)
+)
+
+// parseSeasons reads the season and episode lists of a show's detail page.
+// Episodes without a player link of their own get one derived from the
+// show's vidsrc embed URL.
+func parseSeasons(doc *goquery.Document, showVideoURL string) []Season {
+	bySeason := make(map[int]*Season)
+
+	doc.Find(seasonSelector).Each(func(i int, s *goquery.Selection) {
+		number := attrNumber(s, "data-season")
+		if number == 0 {
+			number = textNumber(seasonNumberPattern, s.ChildrenFiltered(".season-title, h3, h4, span.title").First().Text())
+		}
+		if number == 0 {
+			number = i + 1
+		}
+
+		season, ok := bySeason[number]
+		if !ok {
+			season = &Season{Number: number}
+			bySeason[number] = season
+		}
+
+		s.Find(episodeSelector).Each(func(j int, ep *goquery.Selection) {
+			episode := parseEpisode(ep, j+1)
+			if episode.VideoURL == "" {
+				episode.VideoURL = episodeEmbedURL(showVideoURL, number, episode.Number)
+			} else if doc.Url != nil {
+				if u, err := doc.Url.Parse(episode.VideoURL); err == nil {
+					episode.VideoURL = u.String()
+				}
+			}
+			season.Episodes = append(season.Episodes, episode)
+		})
+	})
+
+	seasons := make([]Season, 0, len(bySeason))
+	for _, s := range bySeason {
+		if len(s.Episodes) == 0 {
+			continue
+		}
+		sort.Slice(s.Episodes, func(a, b int) bool { return s.Episodes[a].Number < s.Episodes[b].Number })
+		seasons = append(seasons, *s)
+	}
+	sort.Slice(seasons, func(a, b int) bool { return seasons[a].Number < seasons[b].Number })
+
+	if len(seasons) == 0 {
+		return nil
+	}
+	return seasons
+}
+
+func parseEpisode(ep *goquery.Selection, position int) Episode {
+	title := strings.TrimSpace(ep.AttrOr("title", ""))
+	if title == "" {
+		title = strings.TrimSpace(ep.Text())
+	}
+
+	number := attrNumber(ep, "data-episode")
+	if number == 0 {
+		number = textNumber(episodeNumberPattern, title)
+	}
+	if number == 0 {
+		number = position
+	}
+
+	videoURL := ""
+	for _, attr := range []string{"data-lazy", "data-src", "data-url"} {
+		if v := ep.AttrOr(attr, ""); v != "" {
+			videoURL = v
+			break
+		}
+	}
+	if videoURL == "" {
+		videoURL = ep.Find("a[href]").AttrOr("href", "")
+	}
+	if videoURL == "#" {
+		videoURL = ""
+	}
+
+	return Episode{
+		Number:   number,
+		Title:    title,
+		VideoURL: videoURL,
+	}
+}
+
+// episodeEmbedURL adds season and episode parameters to a vidsrc tv embed
+// URL such as https://vidsrc.me/embed/tv?imdb=tt0944947.
+func episodeEmbedURL(showVideoURL string, season, episode int) string {
+	if showVideoURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(showVideoURL)
+	if err != nil {
+		return ""
+	}
+
+	q := u.Query()
+	q.Set("season", strconv.Itoa(season))
+	q.Set("episode", strconv.Itoa(episode))
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+func attrNumber(s *goquery.Selection, attr string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s.AttrOr(attr, "")))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func textNumber(re *regexp.Regexp, text string) int {
+	match := re.FindStringSubmatch(text)
+	for _, m := range match[min(1, len(match)):] {
+		if n, err := strconv.Atoi(m); err == nil {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,304 @@
+package scraper
+
+import (
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeSiteRoutes maps request paths on the fake mykadri.tv to the saved
+// pages in testdata.
+var fakeSiteRoutes = map[string]string{
+	"/filmebi_qartulad/page/1/":                "movies_page_1.html",
+	"/filmebi_qartulad/page/2/":                "movies_page_2.html",
+	"/filmebi_qartulad/1001-inception.html":    "movie_inception.html",
+	"/filmebi_qartulad/1002-the-matrix.html":   "movie_the_matrix.html",
+	"/filmebi_qartulad/1003-interstellar.html": "movie_interstellar.html",
+	"/filmebi_qartulad/1004-no-player.html":    "movie_no_player.html",
+	"/filmebi_qartulad/1005-inception-hd.html": "movie_inception_hd.html",
+	"/serialebi_qartulad/page/1/":              "shows_page_1.html",
+	"/serialebi_qartulad/2001-luther.html":     "show_luther.html",
+}
+
+// fakeSite is an httptest server that serves the testdata corpus and
+// records how often each path was requested.
+type fakeSite struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	hits map[string]int
+	// headers holds the headers of the latest request for each path.
+	headers map[string]http.Header
+	// failures makes the next n requests for a path answer with a status.
+	failures map[string]fakeFailure
+}
+
+type fakeFailure struct {
+	status int
+	n      int
+}
+
+func newFakeSite(t *testing.T) *fakeSite {
+	t.Helper()
+
+	site := &fakeSite{
+		hits:     make(map[string]int),
+		headers:  make(map[string]http.Header),
+		failures: make(map[string]fakeFailure),
+	}
+
+	site.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.hits[r.URL.Path]++
+		site.headers[r.URL.Path] = r.Header.Clone()
+		f, failing := site.failures[r.URL.Path]
+		if failing {
+			f.n--
+			if f.n <= 0 {
+				delete(site.failures, r.URL.Path)
+			} else {
+				site.failures[r.URL.Path] = f
+			}
+		}
+		site.mu.Unlock()
+
+		if failing {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(f.status)
+			return
+		}
+
+		name, ok := fakeSiteRoutes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		body, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Errorf("reading fixture %s: %v", name, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(strings.ReplaceAll(string(body), "{{base}}", site.URL)))
+	}))
+	t.Cleanup(site.Close)
+
+	return site
+}
+
+func (s *fakeSite) fail(path string, status, times int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[path] = fakeFailure{status: status, n: times}
+}
+
+func (s *fakeSite) hitCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.hits[path]
+}
+
+func (s *fakeSite) lastHeader(path, key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.headers[path].Get(key)
+}
+
+// newFakeProxy starts an HTTP forward proxy. With status 0 it relays every
+// request; otherwise it answers every request with status itself, like a
+// proxy that cannot reach the site.
+func newFakeProxy(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+
+		out := r.Clone(r.Context())
+		out.RequestURI = ""
+		resp, err := http.DefaultTransport.RoundTrip(out)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		maps.Copy(w.Header(), resp.Header)
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(proxy.Close)
+
+	return proxy
+}
+
+// options returns scraper options pointed at the fake site with no
+// politeness delays and an in-memory store.
+func (s *fakeSite) options(store *memStore) Options {
+	opts := DefaultOptions()
+	opts.BaseURL = s.URL
+	opts.Delay = 0
+	opts.Retry = RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	}
+	opts.Store = store
+	return opts
+}
+
+// memStore is a Store backed by maps, keyed by collection or category name.
+type memStore struct {
+	mu          sync.Mutex
+	links       map[string][]string
+	imdbIDs     map[string][]string
+	saved       map[string][]Item
+	changes     map[string][]Change
+	delisted    map[string][]string
+	runs        []ScrapeRun
+	checkpoints map[string]Checkpoint
+	// checkpointSaves counts SaveCheckpoint calls.
+	checkpointSaves int
+}
+
+func (m *memStore) Links(collection string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.links[collection], nil
+}
+
+func (m *memStore) ImdbIDs(collection string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.imdbIDs[collection], nil
+}
+
+func (m *memStore) Save(collection string, items []Item) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.saved == nil {
+		m.saved = make(map[string][]Item)
+	}
+	m.saved[collection] = append(m.saved[collection], items...)
+	return nil
+}
+
+func (m *memStore) Item(collection, link string) (*Item, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, it := range m.saved[collection] {
+		if it.Link == link {
+			return &it, nil
+		}
+	}
+	return nil, nil
+}
+
+// Update records changes by link without applying them.
+func (m *memStore) Update(collection, link string, changes []Change) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.changes == nil {
+		m.changes = make(map[string][]Change)
+	}
+	m.changes[link] = append(m.changes[link], changes...)
+	return nil
+}
+
+// Delist records which of the collection's stored links were not listed.
+func (m *memStore) Delist(collection string, listed []string, at time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.delisted == nil {
+		m.delisted = make(map[string][]string)
+	}
+	m.delisted[collection] = nil
+	for _, link := range m.links[collection] {
+		if !slices.Contains(listed, link) {
+			m.delisted[collection] = append(m.delisted[collection], link)
+		}
+	}
+	return len(m.delisted[collection]), nil
+}
+
+// SaveRun keeps the latest state of every run, in start order.
+func (m *memStore) SaveRun(run *ScrapeRun) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if run.ID.IsZero() {
+		run.ID = primitive.NewObjectID()
+	}
+	saved := *run
+	saved.Failures = slices.Clone(run.Failures)
+	saved.StatusCounts = maps.Clone(run.StatusCounts)
+
+	for i := range m.runs {
+		if m.runs[i].ID == run.ID {
+			m.runs[i] = saved
+			return nil
+		}
+	}
+	m.runs = append(m.runs, saved)
+	return nil
+}
+
+func (m *memStore) LoadCheckpoint(category string) (*Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cp, ok := m.checkpoints[category]
+	if !ok {
+		return nil, nil
+	}
+	cp.CompletedPages = slices.Clone(cp.CompletedPages)
+	cp.PendingDetails = slices.Clone(cp.PendingDetails)
+	return &cp, nil
+}
+
+func (m *memStore) SaveCheckpoint(cp *Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.checkpoints == nil {
+		m.checkpoints = make(map[string]Checkpoint)
+	}
+	m.checkpointSaves++
+	saved := *cp
+	saved.CompletedPages = slices.Clone(cp.CompletedPages)
+	saved.PendingDetails = slices.Clone(cp.PendingDetails)
+	m.checkpoints[cp.Category] = saved
+	return nil
+}
+
+func (m *memStore) ClearCheckpoint(category string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.checkpoints, category)
+	return nil
+}
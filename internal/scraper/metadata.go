@@ -0,0 +1,165 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	descriptionSelector = "div.full-text, div.fdesc, div.post-description, div.description"
+	infoRowSelector     = "ul.finfo li, div.finfo > div, ul.movie-info li, div.post-info li"
+	ratingSelector      = ".imdb, .rating-imdb, span.imdb-rating"
+)
+
+// infoLabels maps the labels used on detail pages, in Georgian and English,
+// to the Item field they fill.
+var infoLabels = map[string]string{
+	"ჟანრი":        "genres",
+	"ჟანრები":      "genres",
+	"genre":        "genres",
+	"genres":       "genres",
+	"ქვეყანა":      "country",
+	"country":      "country",
+	"რეჟისორი":     "director",
+	"director":     "director",
+	"მსახიობები":   "actors",
+	"როლებში":      "actors",
+	"cast":         "actors",
+	"actors":       "actors",
+	"ხანგრძლივობა": "runtime",
+	"დრო":          "runtime",
+	"duration":     "runtime",
+	"runtime":      "runtime",
+	"imdb":         "rating",
+	"რეიტინგი":     "rating",
+	"rating":       "rating",
+}
+
+var (
+	ratingPattern  = regexp.MustCompile(`(\d+(?:[.,]\d+)?)`)
+	hoursPattern   = regexp.MustCompile(`(?i)(\d+)\s*(?:h|hr|hour|სთ|საათი)`)
+	minutesPattern = regexp.MustCompile(`(?i)(\d+)\s*(?:m|min|minute|წთ|წუთი)`)
+	clockPattern   = regexp.MustCompile(`^(\d{1,2}):(\d{2})(?::\d{2})?$`)
+	numberPattern  = regexp.MustCompile(`\d+`)
+)
+
+// applyMetadata fills the descriptive fields of item from a detail page.
+// Fields that are not present on the page are left empty.
+func applyMetadata(item *Item, doc *goquery.Document) {
+	desc := strings.TrimSpace(doc.Find(descriptionSelector).First().Text())
+	if desc == "" {
+		desc = strings.TrimSpace(doc.Find(`meta[property="og:description"]`).AttrOr("content", ""))
+	}
+	item.Description = collapseSpace(desc)
+
+	doc.Find(infoRowSelector).Each(func(_ int, row *goquery.Selection) {
+		label, value := splitInfoRow(row)
+		switch infoLabels[strings.ToLower(label)] {
+		case "genres":
+			item.Genres = infoList(row, value)
+		case "country":
+			item.Country = strings.Join(infoList(row, value), ", ")
+		case "director":
+			item.Director = strings.Join(infoList(row, value), ", ")
+		case "actors":
+			item.Actors = infoList(row, value)
+		case "runtime":
+			item.Runtime = parseRuntime(value)
+		case "rating":
+			item.Rating = parseRating(value)
+		}
+	})
+
+	if item.Rating == 0 {
+		item.Rating = parseRating(doc.Find(ratingSelector).First().Text())
+	}
+}
+
+// splitInfoRow splits a row such as "<span>ჟანრი:</span> დრამა, თრილერი"
+// into its label and value.
+func splitInfoRow(row *goquery.Selection) (label, value string) {
+	text := collapseSpace(row.Text())
+
+	if l := row.ChildrenFiltered("span, b, strong").First(); l.Length() > 0 {
+		label = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(l.Text()), ":"))
+		value = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(text, collapseSpace(l.Text())), ":"))
+		if label != "" {
+			return label, value
+		}
+	}
+
+	label, value, _ = strings.Cut(text, ":")
+	return strings.TrimSpace(label), strings.TrimSpace(value)
+}
+
+// infoList prefers the texts of the links in a row, which is how the site
+// marks up genres and people, and falls back to splitting value on commas.
+func infoList(row *goquery.Selection, value string) []string {
+	var out []string
+	row.Find("a").Each(func(_ int, a *goquery.Selection) {
+		if t := collapseSpace(a.Text()); t != "" {
+			out = append(out, t)
+		}
+	})
+	if len(out) > 0 {
+		return out
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		if t := strings.TrimSpace(part); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func parseRating(s string) float64 {
+	match := ratingPattern.FindStringSubmatch(s)
+	if len(match) < 2 {
+		return 0
+	}
+
+	r, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil || r < 0 || r > 10 {
+		return 0
+	}
+	return r
+}
+
+// parseRuntime reads running times written as "1h 55m", "115 წთ",
+// "01:55:00" or a bare number of minutes.
+func parseRuntime(s string) int {
+	s = strings.TrimSpace(s)
+
+	if m := clockPattern.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mins, _ := strconv.Atoi(m[2])
+		return h*60 + mins
+	}
+
+	total := 0
+	if m := hoursPattern.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		total += h * 60
+	}
+	if m := minutesPattern.FindStringSubmatch(s); m != nil {
+		mins, _ := strconv.Atoi(m[1])
+		total += mins
+	}
+	if total > 0 {
+		return total
+	}
+
+	if m := numberPattern.FindString(s); m != "" {
+		n, _ := strconv.Atoi(m)
+		return n
+	}
+	return 0
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,118 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Mode int
+
+const (
+	// Incremental walks listing pages newest-first and stops once StopAfter
+	// consecutive pages contain nothing but links we already have.
+	Incremental Mode = iota
+	// Full walks every listing page regardless of what is already stored
+	// and re-reads the detail pages of stored items to pick up changes.
+	Full
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Incremental:
+		return "incremental"
+	case Full:
+		return "full"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "incremental":
+		return Incremental, nil
+	case "full":
+		return Full, nil
+	default:
+		return 0, fmt.Errorf("unknown scrape mode %q", s)
+	}
+}
+
+type Options struct {
+	Mode Mode
+	// StopAfter is the number of consecutive already-seen listing pages an
+	// incremental crawl tolerates before it stops.
+	StopAfter int
+	Retry     RetryPolicy
+	// FirstPage and LastPage restrict the crawl to a range of listing
+	// pages. Zero means from the first page and up to the last one. A crawl
+	// restricted this way only marks titles as delisted if its range covers
+	// every listing page, and neither resumes nor clears the checkpoint of
+	// an interrupted crawl.
+	FirstPage int
+	LastPage  int
+
+	// BaseURL is the site root that category listing paths are joined to.
+	BaseURL string
+	// AllowedDomains restricts which hosts the collector may visit. When
+	// empty it is derived from BaseURL.
+	AllowedDomains []string
+	// Delay is the politeness delay between requests to the site.
+	Delay time.Duration
+	// Proxies are HTTP, HTTPS or SOCKS5 proxy URLs that listing, detail and
+	// pagination requests rotate through. Empty means requests go out
+	// directly.
+	Proxies []string
+	// Cache keeps responses on disk between runs. Off unless Cache.Dir is
+	// set.
+	Cache CacheOptions
+	// Store tells the scraper which items are already stored. Nil means
+	// MongoDB through the models package.
+	Store Store
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Mode:      Incremental,
+		StopAfter: 3,
+		Retry:     DefaultRetryPolicy(),
+		BaseURL:   "https://mykadri.tv",
+		Delay:     2 * time.Second,
+		Cache: CacheOptions{
+			ListingTTL: time.Hour,
+			DetailTTL:  7 * 24 * time.Hour,
+		},
+	}
+}
+
+func (o Options) listingURL(cat *Category) string {
+	return strings.TrimSuffix(o.BaseURL, "/") + cat.ListingPath
+}
+
+func (o Options) allowedDomains() []string {
+	if len(o.AllowedDomains) > 0 {
+		return o.AllowedDomains
+	}
+
+	u, err := url.Parse(o.BaseURL)
+	if err != nil || u.Hostname() == "" {
+		return nil
+	}
+
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	return []string{host, "www." + host}
+}
+
+// ranged reports whether the crawl is restricted to a range of pages.
+func (o Options) ranged() bool {
+	return o.FirstPage > 0 || o.LastPage > 0
+}
+
+func (o Options) store() Store {
+	if o.Store != nil {
+		return o.Store
+	}
+	return mongoStore{}
+}
@@ -0,0 +1,185 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+const postSelector = "div.post.post-t1"
+
+// paginationSelector matches the pagination blocks used across mykadri.tv
+// listing templates.
+const paginationSelector = "div.navigation, div.pagination, div.page-nav, div.nav-links, .wp-pagenavi"
+
+// maxProbePage bounds the probing fallback so a misbehaving site that serves
+// posts for any page number cannot keep us probing forever.
+const maxProbePage = 10000
+
+// discoverLastPage returns the number of the last listing page of
+// urlTemplate. It reads the pagination block on page 1 and falls back to
+// probing for the first empty or missing page if no page links are found.
+// Pages are fetched like the listing pages of the crawl, through c.
+func discoverLastPage(c *colly.Collector, opts Options, urlTemplate string) (int, error) {
+	doc, status, err := fetchListing(c, opts.Retry, fmt.Sprintf(urlTemplate, 1))
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("bad status code on first listing page: %d", status)
+	}
+
+	if last := lastPageFromPagination(doc, urlTemplate); last > 0 {
+		log.Printf("Found %d listing pages from pagination of %s", last, fmt.Sprintf(urlTemplate, 1))
+		return last, nil
+	}
+
+	if doc.Find(postSelector).Length() == 0 {
+		return 0, fmt.Errorf("no posts on first listing page %s", fmt.Sprintf(urlTemplate, 1))
+	}
+
+	last, err := probeLastPage(c, opts, urlTemplate)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Found %d listing pages by probing %s", last, urlTemplate)
+	return last, nil
+}
+
+// lastPageFromPagination returns the highest page number linked from the
+// pagination block of doc, or 0 if there is none.
+func lastPageFromPagination(doc *goquery.Document, urlTemplate string) int {
+	re := pageNumberPattern(urlTemplate)
+	if re == nil {
+		return 0
+	}
+
+	last := 0
+	doc.Find(paginationSelector).Find("a[href]").Each(func(_ int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		if n := pageNumberFromHref(re, doc.Url, href); n > last {
+			last = n
+		}
+	})
+
+	return last
+}
+
+// pageNumberPattern turns a listing template such as
+// "https://mykadri.tv/filmebi_qartulad/page/%d/" into a regexp that captures
+// the page number from a link path.
+func pageNumberPattern(urlTemplate string) *regexp.Regexp {
+	before, after, ok := strings.Cut(urlTemplate, "%d")
+	if !ok {
+		return nil
+	}
+
+	u, err := url.Parse(before)
+	if err != nil {
+		return nil
+	}
+
+	pattern := "^" + regexp.QuoteMeta(u.Path) + `(\d+)` + regexp.QuoteMeta(strings.TrimSuffix(after, "/")) + "/?$"
+	return regexp.MustCompile(pattern)
+}
+
+func pageNumberFromHref(re *regexp.Regexp, base *url.URL, href string) int {
+	u, err := url.Parse(href)
+	if err != nil {
+		return 0
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	match := re.FindStringSubmatch(u.Path)
+	if len(match) < 2 {
+		return 0
+	}
+
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// probeLastPage finds the last non-empty listing page by doubling the page
+// number until a page is empty or missing and then bisecting the gap.
+func probeLastPage(c *colly.Collector, opts Options, urlTemplate string) (int, error) {
+	good, bad := 1, 2
+	for {
+		ok, err := pageHasPosts(c, opts, fmt.Sprintf(urlTemplate, bad))
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			break
+		}
+		if bad >= maxProbePage {
+			return maxProbePage, nil
+		}
+		good, bad = bad, min(bad*2, maxProbePage)
+	}
+
+	for bad-good > 1 {
+		mid := (good + bad) / 2
+		ok, err := pageHasPosts(c, opts, fmt.Sprintf(urlTemplate, mid))
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+
+	return good, nil
+}
+
+func pageHasPosts(c *colly.Collector, opts Options, pageURL string) (bool, error) {
+	doc, status, err := fetchListing(c, opts.Retry, pageURL)
+	if err != nil {
+		return false, err
+	}
+	if status == http.StatusNotFound {
+		return false, nil
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("bad status code probing %s: %d", pageURL, status)
+	}
+
+	return doc.Find(postSelector).Length() > 0, nil
+}
+
+// fetchListing GETs a listing page through c, retrying per policy, and
+// parses it. A non-200 response is not an error; the caller decides what the
+// status means.
+func fetchListing(c *colly.Collector, policy RetryPolicy, pageURL string) (*goquery.Document, int, error) {
+	resp, err := getWithRetry(c, policy, pageURL)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to parse page: %w", err)
+	}
+	doc.Url = resp.Request.URL
+
+	return doc, resp.StatusCode, nil
+}
@@ -0,0 +1,180 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+)
+
+type ProxyStats = models.ProxyStats
+
+const (
+	// proxyEjectAfter is how many failures in a row take a proxy out of
+	// rotation.
+	proxyEjectAfter = 3
+	// proxyCooldown is how long an ejected proxy stays out of rotation.
+	proxyCooldown = 2 * time.Minute
+)
+
+type proxy struct {
+	url     *url.URL
+	stats   ProxyStats
+	failRun int
+	ejected time.Time
+}
+
+// proxyPool hands out proxies round-robin, skipping those that were ejected
+// for failing too often until their cooldown is over.
+type proxyPool struct {
+	mu      sync.Mutex
+	proxies []*proxy
+	next    int
+	now     func() time.Time
+}
+
+// newProxyPool parses HTTP, HTTPS and SOCKS5 proxy URLs. It returns nil
+// when there are none.
+func newProxyPool(rawURLs []string) (*proxyPool, error) {
+	if len(rawURLs) == 0 {
+		return nil, nil
+	}
+
+	pool := &proxyPool{now: time.Now}
+	for _, raw := range rawURLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https or socks5", u.Redacted())
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q: no host", u.Redacted())
+		}
+
+		pool.proxies = append(pool.proxies, &proxy{url: u, stats: ProxyStats{URL: u.Redacted()}})
+	}
+	return pool, nil
+}
+
+// pick returns the next proxy in rotation. When every proxy is ejected it
+// returns the one that comes back soonest rather than going out directly.
+func (p *proxyPool) pick() *proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for range p.proxies {
+		pr := p.proxies[p.next]
+		p.next = (p.next + 1) % len(p.proxies)
+		if !now.Before(pr.ejected) {
+			return pr
+		}
+	}
+
+	soonest := p.proxies[0]
+	for _, pr := range p.proxies[1:] {
+		if pr.ejected.Before(soonest.ejected) {
+			soonest = pr
+		}
+	}
+	return soonest
+}
+
+// record counts the outcome of a request through pr. Transport errors,
+// rate limits and the statuses proxies answer with when they cannot reach
+// the site are failures; proxyEjectAfter of them in a row eject pr.
+func (p *proxyPool) record(pr *proxy, status int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pr.stats.Requests++
+
+	failed := err != nil
+	switch status {
+	case http.StatusTooManyRequests:
+		pr.stats.RateLimited++
+		failed = true
+	case http.StatusProxyAuthRequired, http.StatusBadGateway, http.StatusGatewayTimeout:
+		failed = true
+	}
+
+	if !failed {
+		pr.failRun = 0
+		return
+	}
+
+	pr.stats.Failures++
+	pr.failRun++
+	if pr.failRun >= proxyEjectAfter {
+		pr.failRun = 0
+		pr.ejected = p.now().Add(proxyCooldown)
+		pr.stats.Ejections++
+	}
+}
+
+func (p *proxyPool) stats() []ProxyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]ProxyStats, 0, len(p.proxies))
+	for _, pr := range p.proxies {
+		stats = append(stats, pr.stats)
+	}
+	return stats
+}
+
+type proxyKey struct{}
+
+// proxyTransport sends every request through the next proxy of pool and
+// reports how it went.
+type proxyTransport struct {
+	pool *proxyPool
+	base *http.Transport
+}
+
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	pr := t.pool.pick()
+
+	resp, err := t.base.RoundTrip(req.WithContext(context.WithValue(req.Context(), proxyKey{}, pr)))
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	t.pool.record(pr, status, err)
+
+	return resp, err
+}
+
+// withProxies returns a copy of client whose requests rotate through pool.
+// The caller's client and transport are left untouched.
+func withProxies(client *http.Client, pool *proxyPool) (*http.Client, error) {
+	base, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		base, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok {
+		return nil, errors.New("proxies need the client to use an *http.Transport")
+	}
+
+	base = base.Clone()
+	base.Proxy = func(req *http.Request) (*url.URL, error) {
+		if pr, ok := req.Context().Value(proxyKey{}).(*proxy); ok {
+			return pr.url, nil
+		}
+		return nil, nil
+	}
+
+	proxied := *client
+	proxied.Transport = &proxyTransport{pool: pool, base: base}
+	return &proxied, nil
+}
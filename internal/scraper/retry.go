@@ -0,0 +1,224 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+type RetryPolicy struct {
+	// MaxAttempts caps how many times a single URL is requested, including
+	// the first attempt.
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    2 * time.Minute,
+	}
+}
+
+// retryable reports whether a response status is worth another attempt.
+// Status 0 means the request failed before any response arrived.
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// delay returns how long to wait before the next attempt after attempt
+// failed. A Retry-After header wins over the computed backoff; otherwise the
+// delay grows exponentially from BaseDelay with up to 50% jitter.
+func (p RetryPolicy) delay(attempt int, header http.Header) time.Duration {
+	if d, ok := parseRetryAfter(header.Get("Retry-After"), time.Now()); ok {
+		return min(d, p.MaxDelay)
+	}
+
+	d := p.BaseDelay << min(attempt-1, 16)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter reads a Retry-After value in either delay-seconds or
+// HTTP-date form.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+
+	return 0, false
+}
+
+// Failure records a URL we gave up on.
+type Failure struct {
+	URL      string
+	Attempts int
+	Status   int
+	Reason   string
+}
+
+type failureLog struct {
+	mu       sync.Mutex
+	failures []Failure
+}
+
+func (l *failureLog) add(f Failure) {
+	log.Printf("Gave up on %s after %d attempt(s): %s", f.URL, f.Attempts, f.Reason)
+
+	l.mu.Lock()
+	l.failures = append(l.failures, f)
+	l.mu.Unlock()
+}
+
+func (l *failureLog) list() []Failure {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Failure(nil), l.failures...)
+}
+
+const attemptKey = "attempt"
+
+// handleErrors retries failed collector requests according to p and records
+// a Failure once a request runs out of attempts or is not retryable. If
+// giveUp is not nil it is called with every request given up on.
+func handleErrors(c *colly.Collector, p RetryPolicy, failures *failureLog, giveUp func(*colly.Request)) {
+	fail := func(r *colly.Request, f Failure) {
+		failures.add(f)
+		if giveUp != nil {
+			giveUp(r)
+		}
+	}
+
+	c.OnError(func(r *colly.Response, err error) {
+		if r == nil || r.Request == nil {
+			log.Printf("Request error: %v", err)
+			return
+		}
+
+		attempt, _ := r.Request.Ctx.GetAny(attemptKey).(int)
+		attempt++
+		r.Request.Ctx.Put(attemptKey, attempt)
+
+		url := r.Request.URL.String()
+		reason := err.Error()
+		if r.StatusCode != 0 {
+			reason = fmt.Sprintf("status %d", r.StatusCode)
+		}
+
+		if !retryable(r.StatusCode) || attempt >= p.MaxAttempts {
+			fail(r.Request, Failure{URL: url, Attempts: attempt, Status: r.StatusCode, Reason: reason})
+			return
+		}
+
+		var header http.Header
+		if r.Headers != nil {
+			header = *r.Headers
+		}
+		wait := p.delay(attempt, header)
+		log.Printf("Request to %s failed (%s), retrying in %s (attempt %d/%d)", url, reason, wait.Round(time.Millisecond), attempt+1, p.MaxAttempts)
+
+		time.Sleep(wait)
+		if err := r.Request.Retry(); err != nil {
+			fail(r.Request, Failure{URL: url, Attempts: attempt, Status: r.StatusCode, Reason: err.Error()})
+		}
+	})
+}
+
+// getWithRetry performs a GET on url through a synchronous clone of c,
+// retrying transport errors, 429 and 5xx responses according to p. As a
+// clone it sends the same headers and user agent as c and shares its HTTP
+// client and limit rules. Any other response is returned to the caller as
+// is. When all attempts fail it returns a *GaveUpError.
+func getWithRetry(c *colly.Collector, p RetryPolicy, url string) (*colly.Response, error) {
+	fetch := c.Clone()
+	fetch.Async = false
+	fetch.AllowURLRevisit = true
+	fetch.ParseHTTPErrorResponse = true
+	// The caller needs the page as it is now, not a cached copy.
+	fetch.CacheDir = ""
+	configureRequests(fetch)
+
+	var resp *colly.Response
+	fetch.OnResponse(func(r *colly.Response) { resp = r })
+
+	for attempt := 1; ; attempt++ {
+		resp = nil
+		err := fetch.Visit(url)
+
+		status := 0
+		var header http.Header
+		reason := ""
+		switch {
+		case err != nil:
+			reason = err.Error()
+		case resp == nil:
+			reason = "no response"
+		case !retryable(resp.StatusCode):
+			return resp, nil
+		default:
+			status = resp.StatusCode
+			if resp.Headers != nil {
+				header = *resp.Headers
+			}
+			reason = fmt.Sprintf("status %d", status)
+		}
+
+		if attempt >= p.MaxAttempts {
+			return nil, &GaveUpError{URL: url, Attempts: attempt, Status: status, Reason: reason}
+		}
+
+		wait := p.delay(attempt, header)
+		log.Printf("Request to %s failed (%s), retrying in %s (attempt %d/%d)", url, reason, wait.Round(time.Millisecond), attempt+1, p.MaxAttempts)
+		time.Sleep(wait)
+	}
+}
+
+type GaveUpError struct {
+	URL      string
+	Attempts int
+	Status   int
+	Reason   string
+}
+
+func (e *GaveUpError) Error() string {
+	return fmt.Sprintf("gave up on %s after %d attempt(s): %s", e.URL, e.Attempts, e.Reason)
+}
+
+// failureFromError turns an error from a detail fetch into a Failure.
+func failureFromError(url string, err error) Failure {
+	var gaveUp *GaveUpError
+	if errors.As(err, &gaveUp) {
+		return Failure{URL: url, Attempts: gaveUp.Attempts, Status: gaveUp.Status, Reason: gaveUp.Reason}
+	}
+
+	reason := "unknown error"
+	if err != nil {
+		reason = err.Error()
+	}
+	return Failure{URL: url, Attempts: 1, Reason: reason}
+}
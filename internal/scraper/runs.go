@@ -0,0 +1,120 @@
+package scraper
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/gocolly/colly/v2"
+)
+
+type (
+	ScrapeRun  = models.ScrapeRun
+	RunFailure = models.RunFailure
+)
+
+// runStats counts what a crawl does for its ScrapeRun record.
+type runStats struct {
+	mu       sync.Mutex
+	pages    int
+	found    int
+	skipped  int
+	statuses map[string]int
+}
+
+func newRunStats() *runStats {
+	return &runStats{statuses: make(map[string]int)}
+}
+
+func (s *runStats) addPage() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages++
+}
+
+func (s *runStats) addFound() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.found++
+}
+
+func (s *runStats) addSkipped() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.skipped++
+}
+
+func (s *runStats) addStatus(code int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses[strconv.Itoa(code)]++
+}
+
+// countStatuses adds the status of every response c gets, successful or
+// not, to the histogram. Retried requests count once per attempt.
+func (s *runStats) countStatuses(c *colly.Collector) {
+	c.OnResponse(func(r *colly.Response) {
+		s.addStatus(r.StatusCode)
+	})
+	c.OnError(func(r *colly.Response, _ error) {
+		if r != nil {
+			s.addStatus(r.StatusCode)
+		}
+	})
+}
+
+// startRun records the start of a crawl. A run that cannot be saved is
+// logged and otherwise ignored; it must not stop the scrape.
+func (cr *crawl) startRun() {
+	cr.run = &ScrapeRun{
+		Category:  cr.cat.Name,
+		Mode:      cr.opts.Mode.String(),
+		StartedAt: time.Now(),
+	}
+	if err := cr.store.SaveRun(cr.run); err != nil {
+		log.Printf("Failed to save %s scrape run: %v", cr.cat.Name, err)
+	}
+}
+
+// finishRun fills in the outcome of the crawl and saves its run record.
+// err is the error the crawl stopped with, if any.
+func (cr *crawl) finishRun(err error) {
+	cr.mu.Lock()
+	cr.run.New = len(cr.items)
+	cr.run.Updated = cr.updated
+	cr.mu.Unlock()
+
+	cr.stats.mu.Lock()
+	cr.run.PagesVisited = cr.stats.pages
+	cr.run.ItemsFound = cr.stats.found
+	cr.run.Skipped = cr.stats.skipped
+	cr.run.StatusCounts = make(map[string]int, len(cr.stats.statuses))
+	for code, n := range cr.stats.statuses {
+		cr.run.StatusCounts[code] = n
+	}
+	cr.stats.mu.Unlock()
+
+	cr.run.Failures = nil
+	for _, f := range cr.failures.list() {
+		cr.run.Failures = append(cr.run.Failures, RunFailure(f))
+	}
+
+	if cr.proxies != nil {
+		cr.run.Proxies = cr.proxies.stats()
+	}
+
+	if err != nil {
+		cr.run.Error = err.Error()
+	}
+	cr.run.FinishedAt = time.Now()
+
+	if err := cr.store.SaveRun(cr.run); err != nil {
+		log.Printf("Failed to save %s scrape run: %v", cr.cat.Name, err)
+	}
+}
@@ -0,0 +1,459 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/extensions"
+)
+
+type Item = models.Item
+
+var (
+	yearPattern = regexp.MustCompile(`\((\d{4})\)`)
+	imdbPattern = regexp.MustCompile(`imdb=(tt\d+)`)
+)
+
+// crawl is the state of one Scrape call.
+type crawl struct {
+	cat     *Category
+	opts    Options
+	store   Store
+	details *colly.Collector
+
+	mu       sync.Mutex
+	seen     map[string]struct{}
+	seenImdb map[string]struct{}
+	queued   map[string]struct{}
+	listed   map[string]struct{}
+	items    []Item
+	updated  int
+
+	tally      *pageTally
+	failures   *failureLog
+	checkpoint *checkpointer
+	stats      *runStats
+	proxies    *proxyPool
+	run        *ScrapeRun
+}
+
+// Scrape crawls the listing pages of cat, saves the items whose links and
+// IMDb IDs are not yet stored in cat.Collection as it goes, and returns them.
+// An interrupted crawl resumes from its checkpoint on the next call. Every
+// call that gets as far as loading the checkpoint is recorded as a
+// ScrapeRun.
+func Scrape(client *http.Client, cat *Category, opts Options) (_ []Item, err error) {
+	store := opts.store()
+
+	proxies, err := newProxyPool(opts.Proxies)
+	if err != nil {
+		return nil, err
+	}
+	if proxies != nil {
+		client, err = withProxies(client, proxies)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	existingLinks, err := store.Links(cat.Collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preload %s links: %w", cat.Name, err)
+	}
+
+	existingImdbIDs, err := store.ImdbIDs(cat.Collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to preload %s IMDb IDs: %w", cat.Name, err)
+	}
+
+	cr := &crawl{
+		cat:      cat,
+		opts:     opts,
+		store:    store,
+		seen:     make(map[string]struct{}, len(existingLinks)),
+		seenImdb: make(map[string]struct{}, len(existingImdbIDs)),
+		queued:   make(map[string]struct{}),
+		listed:   make(map[string]struct{}),
+		tally:    newPageTally(),
+		failures: &failureLog{},
+		stats:    newRunStats(),
+		proxies:  proxies,
+	}
+	for _, link := range existingLinks {
+		cr.seen[link] = struct{}{}
+	}
+	for _, id := range existingImdbIDs {
+		cr.seenImdb[id] = struct{}{}
+	}
+
+	if opts.ranged() {
+		cr.checkpoint = memoryCheckpoint(cat, opts.Mode)
+	} else {
+		cr.checkpoint, err = startCheckpoint(store, cat, opts.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s checkpoint: %w", cat.Name, err)
+		}
+	}
+	defer cr.checkpoint.close()
+
+	cr.startRun()
+	defer func() { cr.finishRun(err) }()
+
+	c := setupCollector(client, opts)
+
+	err = c.Limit(&colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: 1,
+		Delay:       opts.Delay,
+		RandomDelay: 500 * time.Microsecond,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	useCache(c, opts.Cache)
+	cr.stats.countStatuses(c)
+
+	cr.details = detailCollector(c)
+	useCache(cr.details, opts.Cache)
+	cr.stats.countStatuses(cr.details)
+	cr.details.OnResponse(cr.handleDetail)
+	handleErrors(cr.details, opts.Retry, cr.failures, func(r *colly.Request) {
+		if item, ok := r.Ctx.GetAny(itemKey).(Item); ok {
+			cr.checkpoint.resolvePending(item.Link)
+		}
+	})
+
+	c.OnHTML(postSelector, func(e *colly.HTMLElement) {
+		item := parseItem(e)
+
+		cr.mu.Lock()
+		cr.listed[item.Link] = struct{}{}
+		cr.mu.Unlock()
+
+		state := cr.claim(item.Link)
+		cr.tally.add(e.Request.URL.String(), state == linkNew)
+		cr.stats.addFound()
+
+		switch state {
+		case linkNew:
+			log.Printf("Found %s: %s (%s)", cat.Kind, item.Title, item.Year)
+			cr.checkpoint.addPending(item)
+			cr.queueDetail(item, e.Request.URL.String(), false)
+		case linkStored:
+			// A full crawl re-reads the detail page of every stored item
+			// to pick up a new player or corrected metadata; an incremental
+			// one only compares what the listing shows.
+			if opts.Mode == Full {
+				cr.queueDetail(item, e.Request.URL.String(), true)
+			} else {
+				cr.updateStored(item, false)
+			}
+		case linkHandled:
+			cr.stats.addSkipped()
+		}
+	})
+
+	c.OnScraped(func(r *colly.Response) {
+		if page, ok := r.Ctx.GetAny(pageKey).(int); ok {
+			cr.checkpoint.completePage(page)
+			cr.stats.addPage()
+		}
+	})
+
+	handleErrors(c, opts.Retry, cr.failures, nil)
+
+	listingURL := opts.listingURL(cat)
+
+	maxPages, err := discoverLastPage(c, opts, listingURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover %s pages: %w", cat.Name, err)
+	}
+	cr.checkpoint.setLastPage(maxPages)
+
+	for _, item := range cr.checkpoint.pending() {
+		if cr.claim(item.Link) == linkNew {
+			log.Printf("Resuming pending %s: %s", cat.Kind, item.Link)
+			cr.queueDetail(item, "", false)
+		}
+	}
+
+	lastPage := maxPages
+	if opts.LastPage > 0 {
+		lastPage = min(lastPage, opts.LastPage)
+	}
+
+	log.Printf("Scraping %s in %s mode", cat.Name, opts.Mode)
+	crawlListing(c, listingURL, lastPage, max(cat.Parallelism, 1), opts, cr.tally, cr.checkpoint.pageDone)
+	cr.details.Wait()
+
+	if opts.Mode == Full {
+		cr.delistMissing()
+	}
+
+	if cr.updated > 0 {
+		log.Printf("Updated %d stored %s", cr.updated, cat.Name)
+	}
+	if n := len(cr.failures.list()); n > 0 {
+		log.Printf("Gave up on %d %s URL(s) this run", n, cat.Kind)
+	}
+
+	cr.checkpoint.finish()
+
+	return cr.items, nil
+}
+
+const (
+	itemKey    = "item"
+	refreshKey = "refresh"
+)
+
+type linkState int
+
+const (
+	// linkNew is a link that is neither stored nor handled yet.
+	linkNew linkState = iota
+	// linkStored is a stored link seen for the first time in this crawl.
+	linkStored
+	// linkHandled is a link this crawl has already dealt with.
+	linkHandled
+)
+
+// claim reports what link is to this crawl and marks it as handled, so a
+// link listed on several pages is only dealt with once.
+func (cr *crawl) claim(link string) linkState {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	if _, found := cr.queued[link]; found {
+		return linkHandled
+	}
+	cr.queued[link] = struct{}{}
+
+	if _, found := cr.seen[link]; found {
+		return linkStored
+	}
+	return linkNew
+}
+
+// queueDetail schedules the detail page of a listing entry on the detail
+// collector. A new entry stays pending in the checkpoint until it has either
+// been saved or definitively failed; with refresh set the entry is already
+// stored and its page is only compared with what is stored.
+func (cr *crawl) queueDetail(item Item, referer string, refresh bool) {
+	ctx := colly.NewContext()
+	ctx.Put(itemKey, item)
+	ctx.Put(refreshKey, refresh)
+
+	var header http.Header
+	if referer != "" {
+		header = http.Header{"Referer": []string{referer}}
+	}
+
+	if err := cr.details.Request(http.MethodGet, item.Link, nil, ctx, header); err != nil {
+		log.Printf("Warning: could not queue detail page for %s: %v", item.Title, err)
+		cr.failures.add(failureFromError(item.Link, err))
+		cr.checkpoint.resolvePending(item.Link)
+	}
+}
+
+// handleDetail parses a fetched detail page and hands it to processItem.
+func (cr *crawl) handleDetail(r *colly.Response) {
+	item, ok := r.Ctx.GetAny(itemKey).(Item)
+	if !ok {
+		return
+	}
+	defer cr.checkpoint.resolvePending(item.Link)
+
+	page, err := goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+	if err != nil {
+		log.Printf("Warning: could not parse detail page for %s: %v", item.Title, err)
+		cr.failures.add(Failure{URL: item.Link, Attempts: 1, Status: r.StatusCode, Reason: "failed to parse page: " + err.Error()})
+		return
+	}
+	page.Url = r.Request.URL
+
+	refresh, _ := r.Ctx.GetAny(refreshKey).(bool)
+	cr.processItem(item, page, string(r.Body), refresh)
+}
+
+// processItem fills in a listing entry from its detail page and saves it,
+// or with refresh set updates the stored item with whatever changed.
+func (cr *crawl) processItem(item Item, page *goquery.Document, body string, refresh bool) {
+	item.Sources = parseSources(page)
+	item.VideoURL = preferredVideoURL(cr.cat.EmbedPattern, body, item.Sources)
+	if item.VideoURL == "" {
+		log.Printf("Warning: no player found for %s", item.Title)
+		cr.failures.add(Failure{URL: item.Link, Attempts: 1, Status: http.StatusOK, Reason: "no player source found in HTML"})
+		if refresh {
+			cr.updateStored(item, false)
+		}
+		return
+	}
+
+	applyMetadata(&item, page)
+	if cr.cat.Kind == KindShow {
+		item.Seasons = parseSeasons(page, item.VideoURL)
+	}
+
+	item.ImdbID = imdbIDFromURL(item.VideoURL)
+	for _, src := range item.Sources {
+		if item.ImdbID != "" {
+			break
+		}
+		item.ImdbID = imdbIDFromURL(src.URL)
+	}
+
+	if refresh {
+		cr.updateStored(item, true)
+		return
+	}
+
+	if !cr.claimItem(item) {
+		cr.stats.addSkipped()
+		return
+	}
+
+	// The claim is held, not the lock, while saving, so other detail pages
+	// are handled in the meantime.
+	if err := cr.store.Save(cr.cat.Collection, []Item{item}); err != nil {
+		log.Printf("Warning: could not save %s: %v", item.Link, err)
+		cr.failures.add(Failure{URL: item.Link, Attempts: 1, Reason: "save failed: " + err.Error()})
+		cr.releaseItem(item)
+		return
+	}
+
+	cr.mu.Lock()
+	cr.items = append(cr.items, item)
+	cr.mu.Unlock()
+}
+
+// claimItem marks the link and IMDb ID of item as stored and reports
+// whether they were free, so two links to the same title are not both
+// saved.
+func (cr *crawl) claimItem(item Item) bool {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	if _, found := cr.seenImdb[item.ImdbID]; found && item.ImdbID != "" {
+		log.Printf("Skipping %s: IMDb ID %s is already stored under another link", item.Link, item.ImdbID)
+		return false
+	}
+	if _, found := cr.seen[item.Link]; found {
+		return false
+	}
+
+	cr.seen[item.Link] = struct{}{}
+	if item.ImdbID != "" {
+		cr.seenImdb[item.ImdbID] = struct{}{}
+	}
+	return true
+}
+
+// releaseItem undoes claimItem for an item that could not be saved.
+func (cr *crawl) releaseItem(item Item) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	delete(cr.seen, item.Link)
+	if item.ImdbID != "" {
+		delete(cr.seenImdb, item.ImdbID)
+	}
+}
+
+func setupCollector(client *http.Client, opts Options) *colly.Collector {
+	c := colly.NewCollector(
+		colly.AllowedDomains(opts.allowedDomains()...),
+		colly.Async(true),
+	)
+
+	c.SetClient(client)
+	configureRequests(c)
+
+	return c
+}
+
+// detailCollector returns the collector detail pages are fetched with. As a
+// clone of the listing collector it shares its HTTP client and limit rules,
+// so listing and detail requests stay within one politeness budget.
+func detailCollector(listing *colly.Collector) *colly.Collector {
+	c := listing.Clone()
+	configureRequests(c)
+
+	return c
+}
+
+// configureRequests sets up the headers, user agent, referer and request
+// logging of a collector. Clones do not inherit callbacks, so every
+// collector needs its own.
+func configureRequests(c *colly.Collector) {
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+		r.Headers.Set("Accept-Language", "en-US,en;q=0.5")
+	})
+
+	extensions.RandomUserAgent(c)
+	extensions.Referer(c)
+
+	c.OnRequest(func(r *colly.Request) {
+		log.Println("Visiting", r.URL.String())
+	})
+}
+
+func parseItem(e *colly.HTMLElement) Item {
+	title := e.DOM.Find("a.post-link.post-title-primary").AttrOr("title", "")
+	englishTitle := e.DOM.Find("a.post-link.post-title-secondary").AttrOr("title", "")
+	link := e.Request.AbsoluteURL(e.DOM.Find("a.post-link.post-title-primary").AttrOr("href", ""))
+
+	year := ""
+	secondaryTitle := e.DOM.Find("a.post-link.post-title-secondary").Text()
+	match := yearPattern.FindStringSubmatch(secondaryTitle)
+	if len(match) > 1 {
+		year = match[1]
+	} else {
+		year = e.DOM.Find("div.yearshort > span.left").Text()
+	}
+
+	img := e.DOM.Find("div.post-image-wrapper img.post-image")
+	imgURL, exists := img.Attr("data-lazy")
+	if !exists {
+		imgURL = img.AttrOr("src", "")
+	}
+	imgURL = e.Request.AbsoluteURL(imgURL)
+
+	return Item{
+		Title:        title,
+		TitleEnglish: englishTitle,
+		Year:         year,
+		Link:         link,
+		Image:        imgURL,
+	}
+}
+
+func imdbIDFromURL(videoURL string) string {
+	match := imdbPattern.FindStringSubmatch(videoURL)
+	if len(match) < 2 {
+		return ""
+	}
+	return match[1]
+}
+
+// preferredVideoURL picks the category's usual player when the page has
+// one and otherwise the first source found.
+func preferredVideoURL(embed *regexp.Regexp, body string, sources []VideoSource) string {
+	if matches := embed.FindStringSubmatch(body); len(matches) > 1 && matches[1] != "" {
+		return matches[1]
+	}
+	if len(sources) > 0 {
+		return sources[0].URL
+	}
+	return ""
+}
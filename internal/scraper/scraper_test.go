@@ -0,0 +1,655 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func findItem(items []Item, englishTitle string) (Item, bool) {
+	for _, it := range items {
+		if it.TitleEnglish == englishTitle {
+			return it, true
+		}
+	}
+	return Item{}, false
+}
+
+func TestScrapeMovies(t *testing.T) {
+	site := newFakeSite(t)
+
+	movies, err := ScrapeMovies(site.Client(), site.options(&memStore{}))
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	// No Player has no player source and Inception HD duplicates
+	// Inception's IMDb ID, so both are dropped.
+	if len(movies) != 3 {
+		t.Fatalf("got %d movies, want 3: %+v", len(movies), movies)
+	}
+
+	inception, ok := findItem(movies, "Inception")
+	if !ok {
+		t.Fatalf("Inception not scraped")
+	}
+
+	want := Movie{
+		Title:        "დასაწყისი",
+		TitleEnglish: "Inception",
+		Year:         "2010",
+		Link:         site.URL + "/filmebi_qartulad/1001-inception.html",
+		Image:        site.URL + "/uploads/posts/2023-01/inception.jpg",
+		VideoURL:     "https://vidsrc.me/embed/movie?imdb=tt1375666",
+		ImdbID:       "tt1375666",
+		Sources: []VideoSource{
+			{Provider: "vidsrc", URL: "https://vidsrc.me/embed/movie?imdb=tt1375666", Language: "ka"},
+			{Provider: "example", URL: "https://vidplay.example.net/e/inception-en", Language: "en"},
+		},
+		Description: "დომ კობი ქურდია, რომელიც სხვის სიზმრებში იპარავს საიდუმლოებს.",
+		Genres:      []string{"მძაფრსიუჟეტიანი", "ფანტასტიკა"},
+		Rating:      8.8,
+		Runtime:     148,
+		Country:     "აშშ, დიდი ბრიტანეთი",
+		Director:    "Christopher Nolan",
+		Actors:      []string{"Leonardo DiCaprio", "Joseph Gordon-Levitt", "Elliot Page"},
+	}
+	if !reflect.DeepEqual(inception, want) {
+		t.Errorf("Inception:\n got %+v\nwant %+v", inception, want)
+	}
+
+	matrix, ok := findItem(movies, "The Matrix")
+	if !ok {
+		t.Fatalf("The Matrix not scraped")
+	}
+	if matrix.Year != "1999" {
+		t.Errorf("The Matrix year = %q, want 1999", matrix.Year)
+	}
+	if matrix.VideoURL != "https://streamtape.example.com/e/matrix" || matrix.ImdbID != "" {
+		t.Errorf("The Matrix video = %q imdb = %q, want the streamtape source and no IMDb ID", matrix.VideoURL, matrix.ImdbID)
+	}
+	if len(matrix.Sources) != 1 || matrix.Sources[0].Language != "ru" {
+		t.Errorf("The Matrix sources = %+v, want one ru source", matrix.Sources)
+	}
+	if matrix.Rating != 8.7 || matrix.Runtime != 136 {
+		t.Errorf("The Matrix rating = %v runtime = %d, want 8.7 and 136", matrix.Rating, matrix.Runtime)
+	}
+	if matrix.Description != "ნეო აღმოაჩენს, რომ სამყარო სიმულაციაა." {
+		t.Errorf("The Matrix description = %q, want the og:description", matrix.Description)
+	}
+
+	if _, ok := findItem(movies, "Interstellar"); !ok {
+		t.Errorf("Interstellar not scraped")
+	}
+}
+
+func TestScrapeSavesItemsAndClearsCheckpoint(t *testing.T) {
+	site := newFakeSite(t)
+	store := &memStore{}
+
+	movies, err := ScrapeMovies(site.Client(), site.options(store))
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if len(store.saved["movies"]) != len(movies) {
+		t.Errorf("saved %d movies, returned %d", len(store.saved["movies"]), len(movies))
+	}
+	if _, ok := store.checkpoints["movies"]; ok {
+		t.Errorf("checkpoint left behind after a completed crawl")
+	}
+	if store.checkpointSaves != 1 {
+		// Once up front; the crawl ends before the first batched write.
+		t.Errorf("checkpoint written %d times, want 1", store.checkpointSaves)
+	}
+}
+
+func TestCheckpointWritesChangesOnClose(t *testing.T) {
+	store := &memStore{}
+	cp, err := startCheckpoint(store, MovieCategory, Full)
+	if err != nil {
+		t.Fatalf("startCheckpoint: %v", err)
+	}
+
+	cp.addPending(Item{Link: "/1-inception"})
+	cp.completePage(1)
+	cp.close()
+
+	saved := store.checkpoints["movies"]
+	if len(saved.PendingDetails) != 1 || !slices.Equal(saved.CompletedPages, []int{1}) {
+		t.Errorf("checkpoint after close = %+v, want the pending item and page 1", saved)
+	}
+	if store.checkpointSaves != 2 {
+		t.Errorf("checkpoint written %d times, want 2", store.checkpointSaves)
+	}
+}
+
+func TestScrapeResumesFromCheckpoint(t *testing.T) {
+	site := newFakeSite(t)
+
+	store := &memStore{checkpoints: map[string]Checkpoint{
+		"movies": {
+			Category:       "movies",
+			Mode:           Incremental.String(),
+			LastPage:       2,
+			CompletedPages: []int{1},
+			PendingDetails: []Item{{
+				Title:        "ვარსკვლავთშორისი",
+				TitleEnglish: "Interstellar",
+				Link:         site.URL + "/filmebi_qartulad/1003-interstellar.html",
+			}},
+		},
+	}}
+
+	movies, err := ScrapeMovies(site.Client(), site.options(store))
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if n := site.hitCount("/filmebi_qartulad/page/1/"); n != 1 {
+		// Page 1 is still fetched once to discover the page count.
+		t.Errorf("page 1 requested %d times, want 1", n)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1001-inception.html"); n != 0 {
+		t.Errorf("completed page's movie fetched %d times, want 0", n)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1003-interstellar.html"); n != 1 {
+		t.Errorf("pending Interstellar fetched %d times, want 1", n)
+	}
+
+	// Interstellar is both pending and listed on page 2; it must only be
+	// saved once next to page 2's Inception HD.
+	if len(movies) != 2 {
+		t.Errorf("got %d movies, want Interstellar and Inception HD: %+v", len(movies), movies)
+	}
+	if _, ok := store.checkpoints["movies"]; ok {
+		t.Errorf("checkpoint left behind after the resumed crawl finished")
+	}
+}
+
+func TestScrapeMoviesIncrementalStopsOnSeenPages(t *testing.T) {
+	site := newFakeSite(t)
+
+	store := &memStore{links: map[string][]string{
+		"movies": {
+			site.URL + "/filmebi_qartulad/1001-inception.html",
+			site.URL + "/filmebi_qartulad/1002-the-matrix.html",
+		},
+	}}
+	opts := site.options(store)
+	opts.StopAfter = 1
+
+	movies, err := ScrapeMovies(site.Client(), opts)
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if len(movies) != 0 {
+		t.Errorf("got %d movies, want none: %+v", len(movies), movies)
+	}
+	if n := site.hitCount("/filmebi_qartulad/page/2/"); n != 0 {
+		t.Errorf("page 2 requested %d times, want 0", n)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1001-inception.html"); n != 0 {
+		t.Errorf("stored movie's detail page requested %d times, want 0", n)
+	}
+}
+
+func TestScrapeMoviesFullVisitsEveryPage(t *testing.T) {
+	site := newFakeSite(t)
+
+	store := &memStore{links: map[string][]string{
+		"movies": {
+			site.URL + "/filmebi_qartulad/1001-inception.html",
+			site.URL + "/filmebi_qartulad/1002-the-matrix.html",
+		},
+	}}
+	opts := site.options(store)
+	opts.Mode = Full
+	opts.StopAfter = 1
+
+	movies, err := ScrapeMovies(site.Client(), opts)
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if n := site.hitCount("/filmebi_qartulad/page/2/"); n != 1 {
+		t.Errorf("page 2 requested %d times, want 1", n)
+	}
+
+	// Page 2 holds Interstellar plus Inception HD, which is new by link and
+	// by IMDb ID since the stored Inception has none recorded here.
+	if len(movies) != 2 {
+		t.Errorf("got %d movies, want 2: %+v", len(movies), movies)
+	}
+}
+
+func TestScrapeRetriesRateLimitedPages(t *testing.T) {
+	site := newFakeSite(t)
+	site.fail("/filmebi_qartulad/page/2/", http.StatusTooManyRequests, 1)
+	site.fail("/filmebi_qartulad/1003-interstellar.html", http.StatusServiceUnavailable, 2)
+
+	movies, err := ScrapeMovies(site.Client(), site.options(&memStore{}))
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if _, ok := findItem(movies, "Interstellar"); !ok {
+		t.Errorf("Interstellar not scraped after retries")
+	}
+	if n := site.hitCount("/filmebi_qartulad/1003-interstellar.html"); n != 3 {
+		t.Errorf("Interstellar detail requested %d times, want 3", n)
+	}
+}
+
+func TestScrapeFetchesDetailsLikeListingPages(t *testing.T) {
+	site := newFakeSite(t)
+
+	if _, err := ScrapeMovies(site.Client(), site.options(&memStore{})); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	const detail = "/filmebi_qartulad/1001-inception.html"
+	if ua := site.lastHeader(detail, "User-Agent"); ua == "" || strings.HasPrefix(ua, "Go-http-client") {
+		t.Errorf("detail page User-Agent = %q, want a browser user agent", ua)
+	}
+	if ref, want := site.lastHeader(detail, "Referer"), site.URL+"/filmebi_qartulad/page/1/"; ref != want {
+		t.Errorf("detail page Referer = %q, want the listing page %q", ref, want)
+	}
+	if lang := site.lastHeader(detail, "Accept-Language"); lang == "" {
+		t.Errorf("detail page sent no Accept-Language header")
+	}
+}
+
+func TestDiscoverLastPageFetchesLikeListingPages(t *testing.T) {
+	site := newFakeSite(t)
+	opts := site.options(&memStore{})
+
+	c := setupCollector(site.Client(), opts)
+	last, err := discoverLastPage(c, opts, opts.listingURL(MovieCategory))
+	if err != nil || last != 2 {
+		t.Fatalf("discoverLastPage = %d, %v, want 2", last, err)
+	}
+
+	const page = "/filmebi_qartulad/page/1/"
+	if ua := site.lastHeader(page, "User-Agent"); ua == "" || strings.HasPrefix(ua, "Go-http-client") {
+		t.Errorf("discovery User-Agent = %q, want a browser user agent", ua)
+	}
+	if lang := site.lastHeader(page, "Accept-Language"); lang == "" {
+		t.Errorf("discovery sent no Accept-Language header")
+	}
+}
+
+func TestScrapeShows(t *testing.T) {
+	site := newFakeSite(t)
+
+	shows, err := ScrapeShows(site.Client(), site.options(&memStore{}))
+	if err != nil {
+		t.Fatalf("ScrapeShows: %v", err)
+	}
+
+	if len(shows) != 1 {
+		t.Fatalf("got %d shows, want 1: %+v", len(shows), shows)
+	}
+
+	luther := shows[0]
+	if luther.VideoURL != "https://vidsrc.me/embed/tv?imdb=tt1474684" || luther.ImdbID != "tt1474684" {
+		t.Errorf("Luther video = %q imdb = %q", luther.VideoURL, luther.ImdbID)
+	}
+	if len(luther.Sources) != 1 {
+		t.Errorf("Luther sources = %+v, want only the show player", luther.Sources)
+	}
+
+	wantSeasons := []Season{
+		{Number: 1, Episodes: []Episode{
+			{Number: 1, Title: "სერია 1", VideoURL: "https://vidsrc.me/embed/tv?imdb=tt1474684&season=1&episode=1"},
+			{Number: 2, Title: "სერია 2", VideoURL: "https://vidsrc.me/embed/tv?imdb=tt1474684&season=1&episode=2"},
+		}},
+		{Number: 2, Episodes: []Episode{
+			{Number: 1, Title: "სერია 1", VideoURL: "https://vidsrc.me/embed/tv?episode=1&imdb=tt1474684&season=2"},
+		}},
+	}
+	if !reflect.DeepEqual(luther.Seasons, wantSeasons) {
+		t.Errorf("Luther seasons:\n got %+v\nwant %+v", luther.Seasons, wantSeasons)
+	}
+
+	// The listing has no pagination block, so page 2 is probed and 404s.
+	if n := site.hitCount("/serialebi_qartulad/page/2/"); n != 1 {
+		t.Errorf("page 2 probed %d times, want 1", n)
+	}
+}
+
+func changeFields(changes []Change) map[string]Change {
+	byField := make(map[string]Change)
+	for _, ch := range changes {
+		byField[ch.Field] = ch
+	}
+	return byField
+}
+
+func TestScrapeIncrementalUpdatesListingFields(t *testing.T) {
+	site := newFakeSite(t)
+
+	link := site.URL + "/filmebi_qartulad/1001-inception.html"
+	store := &memStore{
+		links: map[string][]string{"movies": {link}},
+		saved: map[string][]Item{"movies": {{
+			Title:        "დასაწყისი",
+			TitleEnglish: "Inception",
+			Year:         "2009",
+			Link:         link,
+			Image:        site.URL + "/uploads/old/inception.jpg",
+			VideoURL:     "https://vidsrc.me/embed/movie?imdb=tt1375666",
+		}}},
+	}
+
+	if _, err := ScrapeMovies(site.Client(), site.options(store)); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	changes := changeFields(store.changes[link])
+	if len(changes) != 2 {
+		t.Fatalf("got changes %+v, want year and image", store.changes[link])
+	}
+	if ch := changes["year"]; ch.Old != "2009" || ch.New != "2010" {
+		t.Errorf("year change = %+v, want 2009 -> 2010", ch)
+	}
+	if ch := changes["image"]; ch.New != site.URL+"/uploads/posts/2023-01/inception.jpg" || ch.ChangedAt.IsZero() {
+		t.Errorf("image change = %+v, want the new poster and a timestamp", ch)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1001-inception.html"); n != 0 {
+		t.Errorf("stored movie's detail page requested %d times in incremental mode, want 0", n)
+	}
+}
+
+func TestScrapeFullUpdatesDetailFields(t *testing.T) {
+	site := newFakeSite(t)
+
+	link := site.URL + "/filmebi_qartulad/1002-the-matrix.html"
+	store := &memStore{
+		links: map[string][]string{"movies": {link}},
+		saved: map[string][]Item{"movies": {{
+			Title:        "მატრიცა",
+			TitleEnglish: "The Matrix",
+			Year:         "1999",
+			Link:         link,
+			Image:        site.URL + "/uploads/posts/2023-01/the-matrix.jpg",
+			VideoURL:     "https://streamtape.example.com/e/matrix-old",
+			Rating:       8.7,
+			Runtime:      136,
+		}}},
+	}
+	opts := site.options(store)
+	opts.Mode = Full
+
+	if _, err := ScrapeMovies(site.Client(), opts); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	changes := changeFields(store.changes[link])
+	if ch := changes["videoUrl"]; ch.Old != "https://streamtape.example.com/e/matrix-old" || ch.New != "https://streamtape.example.com/e/matrix" {
+		t.Errorf("videoUrl change = %+v, want the new player", ch)
+	}
+	for _, field := range []string{"title", "year", "rating", "runtime"} {
+		if ch, ok := changes[field]; ok {
+			t.Errorf("unchanged %s reported as changed: %+v", field, ch)
+		}
+	}
+	if _, ok := changes["description"]; !ok {
+		t.Errorf("newly found description not recorded, changes: %+v", store.changes[link])
+	}
+}
+
+func TestScrapeFullDelistsMissingTitles(t *testing.T) {
+	site := newFakeSite(t)
+
+	listed := site.URL + "/filmebi_qartulad/1001-inception.html"
+	removed := site.URL + "/filmebi_qartulad/0999-removed.html"
+	store := &memStore{links: map[string][]string{"movies": {listed, removed}}}
+	opts := site.options(store)
+	opts.Mode = Full
+
+	if _, err := ScrapeMovies(site.Client(), opts); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if got := store.delisted["movies"]; !reflect.DeepEqual(got, []string{removed}) {
+		t.Errorf("delisted %v, want only %s", got, removed)
+	}
+}
+
+func TestScrapeDoesNotDelistAfterPartialCrawl(t *testing.T) {
+	site := newFakeSite(t)
+	site.fail("/filmebi_qartulad/page/2/", http.StatusNotFound, 1)
+
+	removed := site.URL + "/filmebi_qartulad/0999-removed.html"
+	store := &memStore{links: map[string][]string{"movies": {removed}}}
+	opts := site.options(store)
+	opts.Mode = Full
+
+	if _, err := ScrapeMovies(site.Client(), opts); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+	if _, ok := store.delisted["movies"]; ok {
+		t.Errorf("delisted %v after a crawl that missed page 2", store.delisted["movies"])
+	}
+
+	opts.Mode = Incremental
+	if _, err := ScrapeMovies(site.Client(), opts); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+	if _, ok := store.delisted["movies"]; ok {
+		t.Errorf("delisted %v after an incremental crawl", store.delisted["movies"])
+	}
+}
+
+func TestScrapeRecordsRun(t *testing.T) {
+	site := newFakeSite(t)
+	site.fail("/filmebi_qartulad/page/2/", http.StatusTooManyRequests, 1)
+	site.fail("/filmebi_qartulad/1003-interstellar.html", http.StatusServiceUnavailable, 2)
+	store := &memStore{}
+
+	if _, err := ScrapeMovies(site.Client(), site.options(store)); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if len(store.runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(store.runs))
+	}
+	run := store.runs[0]
+
+	if run.Category != "movies" || run.Mode != "incremental" {
+		t.Errorf("run category = %q mode = %q", run.Category, run.Mode)
+	}
+	if run.FinishedAt.Before(run.StartedAt) || run.Error != "" {
+		t.Errorf("run started %v, finished %v, error %q", run.StartedAt, run.FinishedAt, run.Error)
+	}
+	if run.PagesVisited != 2 || run.ItemsFound != 5 || run.New != 3 || run.Skipped != 1 {
+		t.Errorf("run pages = %d found = %d new = %d skipped = %d, want 2, 5, 3 and 1",
+			run.PagesVisited, run.ItemsFound, run.New, run.Skipped)
+	}
+	if len(run.Failures) != 1 || run.Failures[0].URL != site.URL+"/filmebi_qartulad/1004-no-player.html" {
+		t.Errorf("run failures = %+v, want only the page without a player", run.Failures)
+	}
+	if run.StatusCounts["429"] != 1 || run.StatusCounts["503"] != 2 || run.StatusCounts["200"] == 0 {
+		t.Errorf("run status counts = %v", run.StatusCounts)
+	}
+}
+
+func TestScrapePageRange(t *testing.T) {
+	site := newFakeSite(t)
+
+	// An interrupted crawl of every page, which the ranged crawl must
+	// neither resume nor clear.
+	interrupted := Checkpoint{
+		Category:       "movies",
+		Mode:           Incremental.String(),
+		LastPage:       2,
+		CompletedPages: []int{2},
+	}
+	store := &memStore{checkpoints: map[string]Checkpoint{"movies": interrupted}}
+
+	opts := site.options(store)
+	opts.FirstPage = 2
+	opts.LastPage = 2
+
+	if _, err := ScrapeMovies(site.Client(), opts); err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	if n := site.hitCount("/filmebi_qartulad/page/1/"); n != 1 {
+		// Page 1 is only fetched to discover the page count.
+		t.Errorf("page 1 requested %d times, want 1", n)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1001-inception.html"); n != 0 {
+		t.Errorf("movie on page 1 fetched %d times, want 0", n)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1003-interstellar.html"); n != 1 {
+		t.Errorf("movie on page 2 fetched %d times, want 1", n)
+	}
+	if got := store.checkpoints["movies"]; !reflect.DeepEqual(got, interrupted) {
+		t.Errorf("checkpoint after a ranged crawl = %+v, want the interrupted one untouched", got)
+	}
+}
+
+func TestScrapeDryRunWritesJSONLines(t *testing.T) {
+	site := newFakeSite(t)
+
+	var out bytes.Buffer
+	store := NewDryRunStore(&out)
+	opts := site.options(nil)
+	opts.Store = store
+
+	movies, err := ScrapeMovies(site.Client(), opts)
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+
+	var written []Item
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var it Item
+		if err := dec.Decode(&it); err != nil {
+			t.Fatalf("decoding dry-run output: %v", err)
+		}
+		written = append(written, it)
+	}
+
+	if len(written) != len(movies) || len(written) != 3 {
+		t.Errorf("wrote %d items for %d scraped movies, want 3", len(written), len(movies))
+	}
+	if _, ok := findItem(written, "Inception"); !ok {
+		t.Errorf("Inception missing from dry-run output")
+	}
+
+	runs := store.Runs()
+	if len(runs) != 1 || runs[0].New != 3 {
+		t.Errorf("dry-run runs = %+v, want one run with 3 new items", runs)
+	}
+}
+
+func TestScrapeRotatesProxiesAndEjectsFailingOnes(t *testing.T) {
+	site := newFakeSite(t)
+	good := newFakeProxy(t, 0)
+	broken := newFakeProxy(t, http.StatusBadGateway)
+	store := &memStore{}
+
+	opts := site.options(store)
+	opts.Proxies = []string{good.URL, broken.URL}
+
+	movies, err := ScrapeMovies(site.Client(), opts)
+	if err != nil {
+		t.Fatalf("ScrapeMovies: %v", err)
+	}
+	if len(movies) != 3 {
+		t.Errorf("scraped %d movies through proxies, want 3", len(movies))
+	}
+
+	if len(store.runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(store.runs))
+	}
+	proxies := store.runs[0].Proxies
+	if len(proxies) != 2 {
+		t.Fatalf("run proxies = %+v, want stats for both", proxies)
+	}
+
+	if proxies[0].URL != good.URL || proxies[0].Requests == 0 || proxies[0].Failures != 0 {
+		t.Errorf("working proxy stats = %+v", proxies[0])
+	}
+	if proxies[1].URL != broken.URL || proxies[1].Failures != proxyEjectAfter || proxies[1].Ejections != 1 {
+		t.Errorf("broken proxy stats = %+v, want %d failures and one ejection", proxies[1], proxyEjectAfter)
+	}
+}
+
+func TestScrapeRejectsInvalidProxies(t *testing.T) {
+	site := newFakeSite(t)
+	opts := site.options(&memStore{})
+	opts.Proxies = []string{"ftp://proxy.example:21"}
+
+	if _, err := ScrapeMovies(site.Client(), opts); err == nil {
+		t.Fatal("ScrapeMovies accepted an ftp proxy")
+	}
+	if n := site.hitCount("/filmebi_qartulad/page/1/"); n != 0 {
+		t.Errorf("listing fetched %d times despite an invalid proxy", n)
+	}
+}
+
+func TestScrapeReusesCachedResponses(t *testing.T) {
+	site := newFakeSite(t)
+	site.fail("/filmebi_qartulad/page/2/", http.StatusTooManyRequests, 1)
+	site.fail("/filmebi_qartulad/1002-the-matrix.html", http.StatusServiceUnavailable, 1)
+
+	opts := site.options(&memStore{})
+	opts.Cache.Dir = t.TempDir()
+
+	movies, err := ScrapeMovies(site.Client(), opts)
+	if err != nil {
+		t.Fatalf("first ScrapeMovies: %v", err)
+	}
+	if len(movies) != 3 {
+		t.Fatalf("first run scraped %d movies, want 3", len(movies))
+	}
+
+	page2 := site.hitCount("/filmebi_qartulad/page/2/")
+	detail := site.hitCount("/filmebi_qartulad/1002-the-matrix.html")
+
+	// A fresh store makes every movie new again, so the second run needs
+	// every page the first one fetched.
+	opts.Store = &memStore{}
+	movies, err = ScrapeMovies(site.Client(), opts)
+	if err != nil {
+		t.Fatalf("second ScrapeMovies: %v", err)
+	}
+	if len(movies) != 3 {
+		t.Errorf("second run scraped %d movies, want 3", len(movies))
+	}
+	if n := site.hitCount("/filmebi_qartulad/page/2/"); n != page2 {
+		t.Errorf("cached listing page fetched %d more times", n-page2)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1002-the-matrix.html"); n != detail {
+		t.Errorf("cached detail page fetched %d more times", n-detail)
+	}
+
+	// With no TTL left every detail page is fetched again.
+	opts.Cache.DetailTTL = 0
+	opts.Store = &memStore{}
+	if _, err := ScrapeMovies(site.Client(), opts); err != nil {
+		t.Fatalf("third ScrapeMovies: %v", err)
+	}
+	if n := site.hitCount("/filmebi_qartulad/1002-the-matrix.html"); n != detail+1 {
+		t.Errorf("expired detail page fetched %d more times, want 1", n-detail)
+	}
+
+	stats, err := ReadCacheStats(opts.Cache.Dir)
+	if err != nil || stats.Entries == 0 {
+		t.Fatalf("cache stats = %+v, %v", stats, err)
+	}
+	removed, err := PurgeCache(opts.Cache.Dir, 0)
+	if err != nil || removed != stats.Entries {
+		t.Errorf("purged %d of %d entries: %v", removed, stats.Entries, err)
+	}
+}
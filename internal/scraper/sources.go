@@ -0,0 +1,174 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+	"github.com/PuerkitoBio/goquery"
+)
+
+type VideoSource = models.VideoSource
+
+const playerSelector = "iframe, [data-lazy]:not(img), [data-src]:not(img), [data-player]"
+
+// tabSelector matches the player tab buttons whose labels name the language
+// of each player.
+const tabSelector = "ul.player-tabs li, div.tabs-sel span, .player-tab"
+
+// ignoredEmbedHosts are iframes that show up on detail pages but are not
+// players for the title itself.
+var ignoredEmbedHosts = []string{
+	"mykadri.tv",
+	"youtube.com",
+	"youtu.be",
+	"facebook.com",
+	"google.com",
+	"googletagmanager.com",
+	"doubleclick.net",
+	"twitter.com",
+}
+
+// languageLabels maps words found in player tab labels to a language code.
+// The first match wins.
+var languageLabels = []struct {
+	word, lang string
+}{
+	{"ქართულ", "ka"},
+	{"georgian", "ka"},
+	{"ინგლისურ", "en"},
+	{"english", "en"},
+	{"original", "en"},
+	{"რუსულ", "ru"},
+	{"russian", "ru"},
+	{"სუბტიტრ", "sub"},
+	{"subtitles", "sub"},
+}
+
+// parseSources returns every player embed on a detail page, in page order
+// and without duplicates.
+func parseSources(doc *goquery.Document) []VideoSource {
+	tabs := doc.Find(tabSelector)
+
+	var sources []VideoSource
+	have := make(map[string]struct{})
+
+	doc.Find(playerSelector).Each(func(i int, s *goquery.Selection) {
+		// Episode players belong to Seasons, not to the title's sources.
+		if s.Closest(seasonSelector).Length() > 0 {
+			return
+		}
+
+		raw := ""
+		for _, attr := range []string{"data-lazy", "data-src", "data-player", "src"} {
+			if v := strings.TrimSpace(s.AttrOr(attr, "")); v != "" {
+				raw = v
+				break
+			}
+		}
+
+		u, ok := embedURL(doc.Url, raw)
+		if !ok {
+			return
+		}
+
+		if _, dup := have[u.String()]; dup {
+			return
+		}
+		have[u.String()] = struct{}{}
+
+		sources = append(sources, VideoSource{
+			Provider: providerName(u.Hostname()),
+			URL:      u.String(),
+			Language: sourceLanguage(s, tabs, len(sources)),
+		})
+	})
+
+	return sources
+}
+
+// embedURL resolves raw against the page URL and reports whether it looks
+// like a third-party player.
+func embedURL(base *url.URL, raw string) (*url.URL, bool) {
+	if raw == "" || strings.HasPrefix(raw, "data:") || strings.HasPrefix(raw, "javascript:") {
+		return nil, false
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, ignored := range ignoredEmbedHosts {
+		if host == ignored || strings.HasSuffix(host, "."+ignored) {
+			return nil, false
+		}
+	}
+
+	if isImagePath(u.Path) {
+		return nil, false
+	}
+
+	return u, true
+}
+
+func isImagePath(path string) bool {
+	path = strings.ToLower(path)
+	for _, ext := range []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".svg"} {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// providerName turns a host such as "www.vidsrc.me" into "vidsrc".
+func providerName(host string) string {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	parts := strings.Split(host, ".")
+	if len(parts) >= 2 {
+		return parts[len(parts)-2]
+	}
+	return host
+}
+
+// sourceLanguage reads the language of a player from its own attributes,
+// falling back to the label of the tab at the same position.
+func sourceLanguage(s *goquery.Selection, tabs *goquery.Selection, position int) string {
+	for _, attr := range []string{"data-lang", "data-language", "title"} {
+		if lang := languageFromLabel(s.AttrOr(attr, "")); lang != "" {
+			return lang
+		}
+	}
+
+	if position < tabs.Length() {
+		return languageFromLabel(tabs.Eq(position).Text())
+	}
+	return ""
+}
+
+func languageFromLabel(label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" {
+		return ""
+	}
+
+	switch label {
+	case "ka", "en", "ru":
+		return label
+	}
+
+	for _, l := range languageLabels {
+		if strings.Contains(label, l.word) {
+			return l.lang
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package scraper
+
+import (
+	"time"
+
+	"github.com/Ka10ken1/mykadri-scraper/internal/models"
+)
+
+// Store is everything the scraper reads from and writes to persistent
+// storage.
+type Store interface {
+	Links(collection string) ([]string, error)
+	ImdbIDs(collection string) ([]string, error)
+	// Save persists newly scraped items as soon as their detail page has
+	// been parsed, so an interrupted crawl does not lose them.
+	Save(collection string, items []Item) error
+	// Item returns the stored item with link, or nil if there is none.
+	Item(collection, link string) (*Item, error)
+	// Update applies changes found by a re-scrape to the item stored under
+	// link and records them in its history.
+	Update(collection, link string, changes []Change) error
+	// Delist marks every stored item whose link is not in listed as
+	// delisted, clears the mark on those that are, and returns how many
+	// items were newly delisted.
+	Delist(collection string, listed []string, at time.Time) (int, error)
+
+	// SaveRun inserts or replaces the record of a scrape run.
+	SaveRun(run *ScrapeRun) error
+
+	LoadCheckpoint(category string) (*Checkpoint, error)
+	SaveCheckpoint(cp *Checkpoint) error
+	ClearCheckpoint(category string) error
+}
+
+type mongoStore struct{}
+
+func (mongoStore) Links(collection string) ([]string, error) {
+	return models.GetAllLinks(collection)
+}
+
+func (mongoStore) ImdbIDs(collection string) ([]string, error) {
+	return models.GetAllImdbIDs(collection)
+}
+
+func (mongoStore) Save(collection string, items []Item) error {
+	return models.InsertItems(collection, items)
+}
+
+func (mongoStore) Item(collection, link string) (*Item, error) {
+	return models.GetItemByLink(collection, link)
+}
+
+func (mongoStore) Update(collection, link string, changes []Change) error {
+	return models.UpdateItem(collection, link, changes)
+}
+
+func (mongoStore) Delist(collection string, listed []string, at time.Time) (int, error) {
+	n, err := models.MarkDelisted(collection, listed, at)
+	return int(n), err
+}
+
+func (mongoStore) SaveRun(run *ScrapeRun) error {
+	return models.SaveScrapeRun(run)
+}
+
+func (mongoStore) LoadCheckpoint(category string) (*Checkpoint, error) {
+	return models.LoadCrawlState(category)
+}
+
+func (mongoStore) SaveCheckpoint(cp *Checkpoint) error {
+	return models.SaveCrawlState(cp)
+}
+
+func (mongoStore) ClearCheckpoint(category string) error {
+	return models.DeleteCrawlState(category)
+}
@@ -0,0 +1,70 @@
+// Package translit romanizes Georgian text so that titles can be searched
+// in either script.
+package translit
+
+import "strings"
+
+// national is the Georgian national romanization system of 2002.
+var national = map[rune]string{
+	'ა': "a", 'ბ': "b", 'გ': "g", 'დ': "d", 'ე': "e", 'ვ': "v", 'ზ': "z",
+	'თ': "t", 'ი': "i", 'კ': "k'", 'ლ': "l", 'მ': "m", 'ნ': "n", 'ო': "o",
+	'პ': "p'", 'ჟ': "zh", 'რ': "r", 'ს': "s", 'ტ': "t'", 'უ': "u", 'ფ': "p",
+	'ქ': "k", 'ღ': "gh", 'ყ': "q'", 'შ': "sh", 'ჩ': "ch", 'ც': "ts", 'ძ': "dz",
+	'წ': "ts'", 'ჭ': "ch'", 'ხ': "kh", 'ჯ': "j", 'ჰ': "h",
+}
+
+// Romanize replaces the Georgian letters in s by their national
+// romanization and leaves everything else as it is.
+func Romanize(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if lat, ok := national[r]; ok {
+			sb.WriteString(lat)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// variants maps the informal spellings people type for Georgian letters to
+// the ones SearchKey keeps. Longer spellings come first so they win.
+var variants = []struct{ from, to string }{
+	{"tz", "ts"},
+	{"th", "t"},
+	{"ph", "p"},
+	{"ch", "ch"},
+	{"kh", "kh"},
+	{"x", "kh"},
+	{"w", "ts"},
+	{"c", "ts"},
+	{"f", "p"},
+	// ქ is often typed q, which the national system uses for ყ, and ყ is
+	// often typed y; all three fold into one letter.
+	{"q", "k"},
+	{"y", "k"},
+}
+
+// SearchKey turns s, in Georgian or Latin letters, into the form titles are
+// matched in: lowercase national romanization without the apostrophes that
+// mark ejectives, with common informal spellings folded in. "შავი", "shavi"
+// and "Shavi" all give "shavi"; "ხ" and "x" both give "kh".
+func SearchKey(s string) string {
+	s = strings.ToLower(Romanize(strings.ToLower(s)))
+	s = strings.NewReplacer("'", "", "’", "", "ʼ", "", "`", "").Replace(s)
+
+	var sb strings.Builder
+outer:
+	for i := 0; i < len(s); {
+		for _, v := range variants {
+			if strings.HasPrefix(s[i:], v.from) {
+				sb.WriteString(v.to)
+				i += len(v.from)
+				continue outer
+			}
+		}
+		sb.WriteByte(s[i])
+		i++
+	}
+	return sb.String()
+}
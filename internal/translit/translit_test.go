@@ -0,0 +1,41 @@
+package translit
+
+import "testing"
+
+func TestRomanize(t *testing.T) {
+	tests := map[string]string{
+		"შავი":        "shavi",
+		"წყალი":       "ts'q'ali",
+		"ჭადრაკი":     "ch'adrak'i",
+		"ხიდი 2":      "khidi 2",
+		"Mixed ჟანრი": "Mixed zhanri",
+	}
+	for in, want := range tests {
+		if got := Romanize(in); got != want {
+			t.Errorf("Romanize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSearchKeyMatchesEitherScript(t *testing.T) {
+	tests := []struct {
+		georgian string
+		typed    []string
+	}{
+		{"შავი", []string{"shavi", "Shavi", "SHAVI"}},
+		{"წყალი", []string{"ts'q'ali", "tsqali", "wyali", "wqali"}},
+		{"ხიდი", []string{"khidi", "xidi"}},
+		{"ცისფერი", []string{"tsisperi", "cisferi", "tzisperi"}},
+		{"ქართული", []string{"kartuli", "qartuli", "karthuli"}},
+		{"ჩიტი", []string{"chit'i", "chiti"}},
+	}
+
+	for _, tt := range tests {
+		want := SearchKey(tt.georgian)
+		for _, typed := range tt.typed {
+			if got := SearchKey(typed); got != want {
+				t.Errorf("SearchKey(%q) = %q, want it to match %q from %s", typed, got, want, tt.georgian)
+			}
+		}
+	}
+}
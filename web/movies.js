@@ -49,6 +49,7 @@ function renderPage() {
     img.src = movie.image;
     img.alt = movie.Title || movie.title || movie.id;
     img.className = "movie-poster";
+    if (movie.color) img.style.backgroundColor = movie.color;
 
     const title = document.createElement("div");
     title.className = "movie-title";
@@ -49,6 +49,7 @@ function renderPage() {
     img.src = show.image;
     img.alt = show.Title || show.title || show.id;
     img.className = "movie-poster";
+    if (show.color) img.style.backgroundColor = show.color;
 
     const title = document.createElement("div");
     title.className = "movie-title";